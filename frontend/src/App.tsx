@@ -1,22 +1,60 @@
 import { useEffect, useState, useCallback, useRef } from 'react';
 import { Request } from './types';
-import { fetchRequests, fetchSourceName, subscribeSSE } from './api';
+import {
+  clearHistory,
+  fetchOpenRequests,
+  fetchRequest,
+  fetchRequestPage,
+  fetchSourceName,
+  subscribeSSE,
+  UnauthorizedError,
+} from './api';
 import Sidebar from './components/Sidebar';
 import RequestDetail from './components/RequestDetail';
 
+// PAGE_SIZE is how many answered requests the sidebar loads at a time.
+// Open requests are always loaded in full.
+const PAGE_SIZE = 100;
+
+// mergeRequests combines lists that may overlap, newest first.
+function mergeRequests(...lists: Request[][]): Request[] {
+  const byId = new Map<number, Request>();
+  for (const list of lists) {
+    for (const r of list) if (!byId.has(r.ID)) byId.set(r.ID, r);
+  }
+  return Array.from(byId.values()).sort(
+    (a, b) => new Date(b.CreatedAt).getTime() - new Date(a.CreatedAt).getTime() || b.ID - a.ID,
+  );
+}
+
 export default function App() {
-  const [requests, setRequests] = useState<Request[]>([]);
+  const [open, setOpen] = useState<Request[]>([]);
+  const [recent, setRecent] = useState<Request[]>([]);
+  const [older, setOlder] = useState<Request[]>([]);
+  const [cursor, setCursor] = useState<string | undefined>();
+  const [total, setTotal] = useState(0);
+  const [loadingMore, setLoadingMore] = useState(false);
+  const [fallback, setFallback] = useState<Request | null>(null);
   const [selectedId, setSelectedId] = useState<number | null>(null);
   const [loading, setLoading] = useState(true);
   const [sourceName, setSourceName] = useState<string>('');
+  const [unauthorized, setUnauthorized] = useState(false);
   const notifPermissionRef = useRef(false);
+  const olderLoaded = useRef(false);
 
   const loadRequests = useCallback(async () => {
     try {
-      const data = await fetchRequests();
-      setRequests(data);
-    } catch {
-      // retry silently
+      const [openData, page] = await Promise.all([fetchOpenRequests(), fetchRequestPage(PAGE_SIZE)]);
+      setOpen(openData);
+      setRecent(page.requests);
+      setTotal(page.total);
+      // Keyset cursors stay valid as new requests arrive, so once older
+      // pages are loaded the next one continues where they ended.
+      if (!olderLoaded.current) setCursor(page.next_cursor);
+      setUnauthorized(false);
+    } catch (err) {
+      // retry silently, unless only a new login link can help
+      if (err instanceof UnauthorizedError) setUnauthorized(true);
     } finally {
       setLoading(false);
     }
@@ -44,7 +82,8 @@ export default function App() {
     const es = subscribeSSE((data) => {
       // Show browser notification
       if ('Notification' in window && Notification.permission === 'granted') {
-        new Notification(`New request from ${data.app_name}`, {
+        const title = data.event === 'new-notification' ? `Update from ${data.app_name}` : `New request from ${data.app_name}`;
+        new Notification(title, {
           body: data.question.slice(0, 120),
           tag: `rishvan-${data.id}`,
         });
@@ -55,6 +94,15 @@ export default function App() {
 
       // Auto-select the new request
       setSelectedId(data.id);
+    }, (data) => {
+      if (data.event === 'request-deleted') {
+        setOlder((prev) => prev.filter((r) => r.ID !== data.id));
+      } else if (data.event === 'requests-deleted') {
+        // Older pages may have lost any of their entries; start over.
+        olderLoaded.current = false;
+        setOlder([]);
+      }
+      loadRequests();
     });
 
     // Fallback polling
@@ -66,7 +114,73 @@ export default function App() {
     };
   }, [loadRequests]);
 
-  const selectedRequest = requests.find((r) => r.ID === selectedId) || null;
+  const loadOlder = useCallback(async () => {
+    if (!cursor) return;
+    setLoadingMore(true);
+    try {
+      const page = await fetchRequestPage(PAGE_SIZE, cursor);
+      olderLoaded.current = true;
+      setOlder((prev) => [...prev, ...page.requests]);
+      setCursor(page.next_cursor);
+    } finally {
+      setLoadingMore(false);
+    }
+  }, [cursor]);
+
+  const removeRequest = useCallback(
+    (id: number) => {
+      setOlder((prev) => prev.filter((r) => r.ID !== id));
+      setSelectedId((current) => (current === id ? null : current));
+      loadRequests();
+    },
+    [loadRequests],
+  );
+
+  const clearAll = useCallback(async () => {
+    if (!window.confirm('Delete every answered, cancelled and timed-out request? Pending questions are kept.')) return;
+    try {
+      await clearHistory();
+    } catch (err: any) {
+      window.alert(err.message || 'Failed to clear history');
+      return;
+    }
+    olderLoaded.current = false;
+    setOlder([]);
+    setSelectedId(null);
+    loadRequests();
+  }, [loadRequests]);
+
+  const requests = mergeRequests(open, recent, older);
+  const listed = requests.find((r) => r.ID === selectedId) || null;
+
+  // A request picked from search may be older than the loaded pages.
+  useEffect(() => {
+    if (selectedId === null || listed) return;
+    let cancelled = false;
+    fetchRequest(selectedId)
+      .then((r) => !cancelled && setFallback(r))
+      .catch(() => {});
+    return () => {
+      cancelled = true;
+    };
+  }, [selectedId, listed]);
+
+  const selectedRequest = listed || (fallback?.ID === selectedId ? fallback : null);
+
+  if (unauthorized) {
+    return (
+      <div className="flex h-screen items-center justify-center bg-gray-950 text-gray-100">
+        <div className="max-w-md text-center space-y-3">
+          <h1 className="text-lg font-semibold">Not logged in</h1>
+          <p className="text-sm text-gray-400">
+            This page needs a one-time login link. Ask a question from your IDE, or run{' '}
+            <code className="rounded bg-gray-800 px-1.5 py-0.5 text-gray-200">rishvan-mcp open</code>{' '}
+            on this machine.
+          </p>
+        </div>
+      </div>
+    );
+  }
 
   return (
     <div className="flex h-screen bg-gray-950 text-gray-100">
@@ -75,10 +189,18 @@ export default function App() {
         selectedId={selectedId}
         onSelect={setSelectedId}
         sourceName={sourceName}
+        hasOlder={!!cursor}
+        loadingOlder={loadingMore}
+        onLoadOlder={loadOlder}
+        total={total}
+        onClearHistory={clearAll}
       />
       <RequestDetail
+        key={selectedRequest?.ID}
         request={selectedRequest}
         onResponded={loadRequests}
+        onSelect={setSelectedId}
+        onDeleted={removeRequest}
       />
     </div>
   );
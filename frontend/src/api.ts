@@ -1,24 +1,81 @@
-import { Request } from './types';
+import { Attachment, Message, Request, RequestPage, SearchResult } from './types';
 
 const BASE = '';
 
-export async function fetchRequests(appName?: string): Promise<Request[]> {
-  const params = appName ? `?app_name=${encodeURIComponent(appName)}` : '';
-  const res = await fetch(`${BASE}/api/requests${params}`);
+// Thrown when the browser has no valid session. Only a fresh one-time
+// login link (opened by the IDE or `rishvan-mcp open`) can fix that.
+export class UnauthorizedError extends Error {
+  constructor() {
+    super('Not logged in');
+    this.name = 'UnauthorizedError';
+  }
+}
+
+// fetchOpenRequests returns every request still waiting for the human,
+// however old.
+export async function fetchOpenRequests(): Promise<Request[]> {
+  const res = await fetch(`${BASE}/api/requests?status=pending,unread`);
+  if (res.status === 401) throw new UnauthorizedError();
+  if (!res.ok) throw new Error(`Failed to fetch requests: ${res.statusText}`);
+  return res.json();
+}
+
+export async function fetchRequestPage(limit: number, cursor?: string): Promise<RequestPage> {
+  const params = new URLSearchParams({ limit: String(limit) });
+  if (cursor) params.set('cursor', cursor);
+  const res = await fetch(`${BASE}/api/requests?${params}`);
+  if (res.status === 401) throw new UnauthorizedError();
   if (!res.ok) throw new Error(`Failed to fetch requests: ${res.statusText}`);
   return res.json();
 }
 
+export async function searchRequests(q: string): Promise<SearchResult[]> {
+  const res = await fetch(`${BASE}/api/requests/search?q=${encodeURIComponent(q)}`);
+  if (res.status === 401) throw new UnauthorizedError();
+  if (!res.ok) throw new Error(`Failed to search requests: ${res.statusText}`);
+  return res.json();
+}
+
 export async function fetchRequest(id: number): Promise<Request> {
   const res = await fetch(`${BASE}/api/requests/${id}`);
   if (!res.ok) throw new Error(`Failed to fetch request: ${res.statusText}`);
   return res.json();
 }
 
+export function attachmentURL(a: Attachment, download = false): string {
+  return `${BASE}/api/requests/${a.request_id}/attachments/${a.index}${download ? '?download=1' : ''}`;
+}
+
+export async function fetchAttachmentText(a: Attachment): Promise<string> {
+  const res = await fetch(attachmentURL(a));
+  if (!res.ok) throw new Error(`Failed to fetch attachment: ${res.statusText}`);
+  return res.text();
+}
+
+// Returns every request of a conversation thread, oldest first.
+export async function fetchThread(threadId: number): Promise<Request[]> {
+  const res = await fetch(`${BASE}/api/threads/${threadId}`);
+  if (!res.ok) throw new Error(`Failed to fetch thread: ${res.statusText}`);
+  return res.json();
+}
+
+// The primary embeds the CSRF token in index.html once the browser has
+// logged in. Pages it did not serve (the Vite dev server) fetch it instead.
+let csrfToken: string | null =
+  document.querySelector<HTMLMetaElement>('meta[name="rishvan-csrf"]')?.content ?? null;
+
+async function csrfHeaders(): Promise<Record<string, string>> {
+  if (!csrfToken) {
+    const res = await fetch(`${BASE}/api/auth/csrf`);
+    if (res.ok) csrfToken = (await res.json()).csrf_token;
+  }
+  return csrfToken ? { 'X-CSRF-Token': csrfToken } : {};
+}
+
 export async function respondToRequest(id: number, response: string): Promise<void> {
   const res = await fetch(`${BASE}/api/requests/${id}/respond`, {
     method: 'POST',
-    headers: { 'Content-Type': 'application/json' },
+    headers: { 'Content-Type': 'application/json', ...(await csrfHeaders()) },
     body: JSON.stringify({ response }),
   });
   if (!res.ok) {
@@ -27,6 +84,93 @@ export async function respondToRequest(id: number, response: string): Promise<vo
   }
 }
 
+export async function respondWithData(id: number, data: Record<string, unknown>): Promise<void> {
+  const res = await fetch(`${BASE}/api/requests/${id}/respond`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json', ...(await csrfHeaders()) },
+    body: JSON.stringify({ data }),
+  });
+  if (!res.ok) {
+    const text = await res.text();
+    throw new Error(text || res.statusText);
+  }
+}
+
+// respondWithFiles sends a free-text answer with files the human attached.
+// The browser sets the multipart Content-Type and boundary itself.
+export async function respondWithFiles(id: number, response: string, files: File[]): Promise<void> {
+  const body = new FormData();
+  body.append('response', response);
+  files.forEach((f) => body.append('files', f, f.name));
+  const res = await fetch(`${BASE}/api/requests/${id}/respond`, {
+    method: 'POST',
+    headers: await csrfHeaders(),
+    body,
+  });
+  if (!res.ok) {
+    const text = await res.text();
+    throw new Error(text || res.statusText);
+  }
+}
+
+export async function acknowledgeRequest(id: number): Promise<void> {
+  const res = await fetch(`${BASE}/api/requests/${id}/acknowledge`, {
+    method: 'POST',
+    headers: await csrfHeaders(),
+  });
+  if (!res.ok) {
+    const text = await res.text();
+    throw new Error(text || res.statusText);
+  }
+}
+
+// Deletes a closed request for good, attachments included.
+export async function deleteRequest(id: number): Promise<void> {
+  const res = await fetch(`${BASE}/api/requests/${id}`, {
+    method: 'DELETE',
+    headers: await csrfHeaders(),
+  });
+  if (!res.ok) {
+    const text = await res.text();
+    throw new Error(text || res.statusText);
+  }
+}
+
+// Deletes every closed request; pending ones are kept. Returns how many
+// were deleted.
+export async function clearHistory(): Promise<number> {
+  const res = await fetch(`${BASE}/api/requests`, {
+    method: 'DELETE',
+    headers: await csrfHeaders(),
+  });
+  if (!res.ok) {
+    const text = await res.text();
+    throw new Error(text || res.statusText);
+  }
+  return (await res.json()).deleted;
+}
+
+export async function fetchMessages(sourceName: string, appName: string): Promise<Message[]> {
+  const params = new URLSearchParams({ source_name: sourceName, app_name: appName });
+  const res = await fetch(`${BASE}/api/messages?${params}`);
+  if (!res.ok) throw new Error(`Failed to fetch messages: ${res.statusText}`);
+  return res.json();
+}
+
+// Queues a message for the agents of a source; they receive it on their
+// next check_rishvan_messages or ask_rishvan call.
+export async function postMessage(sourceName: string, appName: string, body: string): Promise<void> {
+  const res = await fetch(`${BASE}/api/messages`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json', ...(await csrfHeaders()) },
+    body: JSON.stringify({ source_name: sourceName, app_name: appName, body }),
+  });
+  if (!res.ok) {
+    const text = await res.text();
+    throw new Error(text || res.statusText);
+  }
+}
+
 export async function fetchSourceName(): Promise<string> {
   const res = await fetch(`${BASE}/api/ide`);
   if (!res.ok) throw new Error(`Failed to fetch source name: ${res.statusText}`);
@@ -34,15 +178,43 @@ export async function fetchSourceName(): Promise<string> {
   return data.source_name;
 }
 
-export function subscribeSSE(onNewRequest: (data: { id: number; app_name: string; question: string }) => void): EventSource {
+// Events that change an existing request rather than announcing a new one.
+const UPDATE_EVENTS = [
+  'request-responded',
+  'request-timed-out',
+  'request-cancelled',
+  'request-orphaned',
+  'notification-acknowledged',
+  'request-deleted',
+  'requests-deleted',
+];
+
+// Events that add an entry to the feed.
+const NEW_EVENTS = ['new-request', 'new-notification'];
+
+export function subscribeSSE(
+  onNewRequest: (data: { id: number; app_name: string; question: string; event: string }) => void,
+  onUpdate: (data: { id: number; event: string }) => void,
+): EventSource {
   const es = new EventSource(`${BASE}/api/events`);
-  es.addEventListener('new-request', (e) => {
-    try {
-      const data = JSON.parse(e.data);
-      onNewRequest(data);
-    } catch {
-      // ignore parse errors
-    }
-  });
+  for (const name of NEW_EVENTS) {
+    es.addEventListener(name, (e) => {
+      try {
+        const data = JSON.parse((e as MessageEvent).data);
+        onNewRequest({ ...data, event: name });
+      } catch {
+        // ignore parse errors
+      }
+    });
+  }
+  for (const name of UPDATE_EVENTS) {
+    es.addEventListener(name, (e) => {
+      try {
+        onUpdate(JSON.parse((e as MessageEvent).data));
+      } catch {
+        // ignore parse errors
+      }
+    });
+  }
   return es;
 }
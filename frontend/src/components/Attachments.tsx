@@ -0,0 +1,86 @@
+import { useEffect, useState } from 'react';
+import { Attachment } from '../types';
+import { attachmentURL, fetchAttachmentText } from '../api';
+
+// Text attachments larger than this are offered as a download instead of
+// being shown inline.
+const MAX_INLINE_TEXT = 256 * 1024;
+
+export function formatSize(bytes: number): string {
+  if (bytes < 1024) return `${bytes} B`;
+  if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KB`;
+  return `${(bytes / (1024 * 1024)).toFixed(1)} MB`;
+}
+
+function isText(a: Attachment): boolean {
+  return a.mime_type.startsWith('text/') || a.mime_type === 'application/json';
+}
+
+function TextPreview({ attachment }: { attachment: Attachment }) {
+  const [text, setText] = useState<string | null>(null);
+  const [error, setError] = useState(false);
+
+  useEffect(() => {
+    let cancelled = false;
+    fetchAttachmentText(attachment)
+      .then((t) => !cancelled && setText(t))
+      .catch(() => !cancelled && setError(true));
+    return () => {
+      cancelled = true;
+    };
+  }, [attachment.request_id, attachment.index]);
+
+  if (error) return <p className="px-3 py-2 text-xs text-red-400">Failed to load attachment</p>;
+  if (text === null) return <p className="px-3 py-2 text-xs text-gray-500">Loading…</p>;
+
+  const isDiff = attachment.mime_type === 'text/x-diff' || attachment.mime_type === 'text/x-patch';
+  return (
+    <pre className="max-h-96 overflow-auto px-3 py-2 text-xs leading-relaxed text-gray-300">
+      {isDiff
+        ? text.split('\n').map((line, i) => (
+            <div
+              key={i}
+              className={
+                line.startsWith('+') && !line.startsWith('+++')
+                  ? 'text-green-400'
+                  : line.startsWith('-') && !line.startsWith('---')
+                    ? 'text-red-400'
+                    : line.startsWith('@@')
+                      ? 'text-blue-400'
+                      : undefined
+              }
+            >
+              {line || ' '}
+            </div>
+          ))
+        : text}
+    </pre>
+  );
+}
+
+export default function Attachments({ attachments }: { attachments: Attachment[] }) {
+  if (attachments.length === 0) return null;
+  return (
+    <div className="mt-3 flex flex-col gap-3">
+      {attachments.map((a) => (
+        <div key={a.index} className="rounded-lg border border-gray-800 bg-gray-900/40 overflow-hidden">
+          <div className="flex items-center justify-between px-3 py-1.5 border-b border-gray-800 text-xs">
+            <span className="text-gray-300 truncate">{a.name}</span>
+            <span className="flex items-center gap-3 text-gray-600">
+              {formatSize(a.size)}
+              <a href={attachmentURL(a, true)} className="text-blue-400 hover:text-blue-300">
+                Download
+              </a>
+            </span>
+          </div>
+          {a.mime_type.startsWith('image/') && (
+            <a href={attachmentURL(a)} target="_blank" rel="noreferrer">
+              <img src={attachmentURL(a)} alt={a.name} className="max-h-[32rem] mx-auto" />
+            </a>
+          )}
+          {isText(a) && a.size <= MAX_INLINE_TEXT && <TextPreview attachment={a} />}
+        </div>
+      ))}
+    </div>
+  );
+}
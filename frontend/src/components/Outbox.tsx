@@ -0,0 +1,95 @@
+import { useCallback, useEffect, useState } from 'react';
+import { Message } from '../types';
+import { fetchMessages, postMessage } from '../api';
+
+interface OutboxProps {
+  sourceName: string;
+  appName: string;
+}
+
+// Outbox lets the human message an agent that is busy working rather than
+// waiting on a question, e.g. to stop it going in the wrong direction.
+export default function Outbox({ sourceName, appName }: OutboxProps) {
+  const [messages, setMessages] = useState<Message[]>([]);
+  const [body, setBody] = useState('');
+  const [sending, setSending] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+
+  const load = useCallback(async () => {
+    try {
+      setMessages(await fetchMessages(sourceName, appName));
+    } catch {
+      // retry on the next tick
+    }
+  }, [sourceName, appName]);
+
+  useEffect(() => {
+    load();
+    const interval = setInterval(load, 5000);
+    return () => clearInterval(interval);
+  }, [load]);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    const text = body.trim();
+    if (!text) return;
+
+    setSending(true);
+    setError(null);
+    try {
+      await postMessage(sourceName, appName, text);
+      setBody('');
+      await load();
+    } catch (err: any) {
+      setError(err.message || 'Failed to send message');
+    } finally {
+      setSending(false);
+    }
+  };
+
+  const recent = messages.slice(0, 5).reverse();
+
+  return (
+    <div className="mt-8">
+      <div className="mb-2 text-xs font-semibold text-gray-500 uppercase tracking-wider">
+        Message the agent
+      </div>
+      {recent.length > 0 && (
+        <div className="mb-3 flex flex-col gap-1.5">
+          {recent.map((m) => (
+            <div key={m.ID} className="flex items-start gap-2 text-xs">
+              <span className="flex-1 text-gray-300 whitespace-pre-wrap">{m.body}</span>
+              <span className={m.read_at ? 'text-green-500' : 'text-amber-400'}>
+                {m.read_at ? 'delivered' : 'waiting for agent'}
+              </span>
+            </div>
+          ))}
+        </div>
+      )}
+      {error && (
+        <div className="mb-3 px-3 py-2 bg-red-900/30 border border-red-800/50 rounded text-red-300 text-xs">
+          {error}
+        </div>
+      )}
+      <form onSubmit={handleSubmit} className="flex gap-2">
+        <input
+          value={body}
+          onChange={(e) => setBody(e.target.value)}
+          placeholder={`Tell ${appName} something, e.g. "stop, wrong direction"`}
+          className="flex-1 bg-gray-800 border border-gray-700 rounded-lg px-3 py-2 text-sm text-gray-200 placeholder-gray-600 focus:outline-none focus:ring-2 focus:ring-blue-500/50 focus:border-blue-500/50"
+          disabled={sending}
+        />
+        <button
+          type="submit"
+          disabled={sending || !body.trim()}
+          className="px-4 py-2 bg-gray-700 hover:bg-gray-600 disabled:text-gray-500 text-white text-sm rounded-lg transition-colors"
+        >
+          {sending ? 'Sending...' : 'Send'}
+        </button>
+      </form>
+      <p className="mt-1.5 text-[10px] text-gray-600">
+        Delivered the next time the agent checks its messages or asks a question.
+      </p>
+    </div>
+  );
+}
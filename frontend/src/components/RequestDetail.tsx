@@ -1,16 +1,34 @@
-import { useState } from 'react';
+import { useRef, useState } from 'react';
 import { Request } from '../types';
-import { respondToRequest } from '../api';
+import { acknowledgeRequest, deleteRequest, respondToRequest, respondWithData, respondWithFiles } from '../api';
+import { statusStyle } from '../status';
+import SchemaForm from './SchemaForm';
+import Outbox from './Outbox';
+import Attachments, { formatSize } from './Attachments';
+import { EarlierInThread, LaterInThread, useThread } from './ThreadHistory';
 
 interface RequestDetailProps {
   request: Request | null;
   onResponded: () => void;
+  onSelect: (id: number) => void;
+  onDeleted: (id: number) => void;
 }
 
-export default function RequestDetail({ request, onResponded }: RequestDetailProps) {
+function formatJSON(text: string): string {
+  try {
+    return JSON.stringify(JSON.parse(text), null, 2);
+  } catch {
+    return text;
+  }
+}
+
+export default function RequestDetail({ request, onResponded, onSelect, onDeleted }: RequestDetailProps) {
   const [response, setResponse] = useState('');
   const [submitting, setSubmitting] = useState(false);
   const [error, setError] = useState<string | null>(null);
+  const [files, setFiles] = useState<File[]>([]);
+  const fileInput = useRef<HTMLInputElement>(null);
+  const thread = useThread(request);
 
   if (!request) {
     return (
@@ -24,16 +42,20 @@ export default function RequestDetail({ request, onResponded }: RequestDetailPro
   }
 
   const isPending = request.status === 'pending';
+  const isNotification = request.kind === 'notification';
+  const isChoice = request.kind === 'choice';
+  const isForm = request.kind === 'form';
+  const showTextInput = !isForm && (!isChoice || request.allow_other);
 
-  const handleSubmit = async (e: React.FormEvent) => {
-    e.preventDefault();
-    if (!response.trim() || !isPending) return;
+  const send = async (action: () => Promise<void>) => {
+    if (!isPending && request.status !== 'unread') return;
 
     setSubmitting(true);
     setError(null);
     try {
-      await respondToRequest(request.ID, response.trim());
+      await action();
       setResponse('');
+      setFiles([]);
       onResponded();
     } catch (err: any) {
       setError(err.message || 'Failed to send response');
@@ -42,19 +64,48 @@ export default function RequestDetail({ request, onResponded }: RequestDetailPro
     }
   };
 
+  const submit = async (text: string) => {
+    if (!text) return;
+    await send(() => respondToRequest(request.ID, text));
+  };
+
+  const submitText = async (text: string) => {
+    if (files.length === 0) return submit(text);
+    await send(() => respondWithFiles(request.ID, text, files));
+  };
+
+  const remove = async () => {
+    if (!window.confirm(`Delete request #${request.ID} and its attachments? This cannot be undone.`)) return;
+    setError(null);
+    try {
+      await deleteRequest(request.ID);
+      onDeleted(request.ID);
+    } catch (err: any) {
+      setError(err.message || 'Failed to delete request');
+    }
+  };
+
+  const addFiles = (added: FileList | null) => {
+    if (added && added.length > 0) setFiles((prev) => [...prev, ...Array.from(added)]);
+  };
+
+  const askedWith = (request.attachments || []).filter((a) => !a.from_human);
+  const answeredWith = (request.attachments || []).filter((a) => a.from_human);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    await submitText(response.trim());
+  };
+
   return (
     <div className="flex-1 flex flex-col h-full overflow-hidden">
       {/* Header */}
       <div className="px-6 py-4 border-b border-gray-800 bg-gray-900/50">
         <div className="flex items-center gap-3">
           <span
-            className={`inline-block px-2 py-0.5 rounded text-xs font-medium ${
-              isPending
-                ? 'bg-amber-500/20 text-amber-400'
-                : 'bg-green-500/20 text-green-400'
-            }`}
+            className={`inline-block px-2 py-0.5 rounded text-xs font-medium ${statusStyle(request.status).className}`}
           >
-            {isPending ? 'Awaiting Response' : 'Responded'}
+            {statusStyle(request.status).label}
           </span>
           <span className="text-xs text-gray-500">
             {request.app_name}
@@ -62,32 +113,146 @@ export default function RequestDetail({ request, onResponded }: RequestDetailPro
           <span className="text-xs text-gray-600">
             #{request.ID}
           </span>
+          {request.parent_id && (
+            <button
+              type="button"
+              onClick={() => onSelect(request.parent_id!)}
+              className="text-xs text-blue-400 hover:text-blue-300"
+            >
+              Follow-up to #{request.parent_id}
+            </button>
+          )}
+          {isPending && request.expires_at && (
+            <span className="text-xs text-gray-500">
+              Times out at {new Date(request.expires_at).toLocaleTimeString()}
+            </span>
+          )}
+          {!isPending && (
+            <button
+              type="button"
+              onClick={remove}
+              className="ml-auto text-xs text-gray-500 hover:text-red-400"
+            >
+              Delete
+            </button>
+          )}
         </div>
       </div>
 
       {/* Question */}
       <div className="flex-1 overflow-y-auto px-6 py-6">
+        <EarlierInThread request={request} thread={thread} onSelect={onSelect} />
+
         <div className="mb-2 text-xs font-semibold text-gray-500 uppercase tracking-wider">
-          Question
+          {isNotification ? 'Notification' : 'Question'}
         </div>
         <div className="bg-gray-800/50 rounded-lg p-4 text-gray-200 text-sm leading-relaxed whitespace-pre-wrap">
           {request.question}
         </div>
+        <Attachments attachments={askedWith} />
+
+        {isNotification && request.status === 'unread' && (
+          <div className="mt-4">
+            {error && (
+              <div className="mb-3 px-3 py-2 bg-red-900/30 border border-red-800/50 rounded text-red-300 text-xs">
+                {error}
+              </div>
+            )}
+            <button
+              type="button"
+              onClick={() => send(() => acknowledgeRequest(request.ID))}
+              disabled={submitting}
+              className="px-4 py-2 bg-blue-600 hover:bg-blue-500 disabled:bg-gray-700 disabled:text-gray-500 text-white text-sm font-medium rounded-lg transition-colors"
+            >
+              {submitting ? 'Acknowledging...' : 'Acknowledge'}
+            </button>
+            <p className="mt-2 text-[10px] text-gray-600">The agent is not waiting for a reply.</p>
+          </div>
+        )}
+
+        {request.status === 'orphaned' && (
+          <div className="mt-6 px-4 py-3 bg-purple-900/20 border border-purple-800/30 rounded-lg text-purple-200 text-xs leading-relaxed">
+            The agent that asked this question is no longer running, so an answer cannot be delivered.
+            If the agent asks the same question again, it will reappear here as pending.
+          </div>
+        )}
+
+        {isPending && isChoice && (
+          <>
+            <div className="mt-6 mb-2 text-xs font-semibold text-gray-500 uppercase tracking-wider">
+              Options
+            </div>
+            {error && !showTextInput && (
+              <div className="mb-3 px-3 py-2 bg-red-900/30 border border-red-800/50 rounded text-red-300 text-xs">
+                {error}
+              </div>
+            )}
+            <div className="flex flex-col gap-2">
+              {(request.options || []).map((opt) => (
+                <button
+                  key={opt.label}
+                  type="button"
+                  onClick={() => submit(opt.label)}
+                  disabled={submitting}
+                  className={`text-left px-4 py-3 rounded-lg border transition-colors disabled:opacity-50 ${
+                    opt.label === request.default_option
+                      ? 'border-blue-500/60 bg-blue-600/20 hover:bg-blue-600/30'
+                      : 'border-gray-700 bg-gray-800/50 hover:bg-gray-800'
+                  }`}
+                >
+                  <div className="text-sm font-medium text-gray-100">
+                    {opt.label}
+                    {opt.label === request.default_option && (
+                      <span className="ml-2 text-[10px] text-blue-400 uppercase tracking-wider">Recommended</span>
+                    )}
+                  </div>
+                  {opt.description && (
+                    <div className="mt-0.5 text-xs text-gray-400">{opt.description}</div>
+                  )}
+                </button>
+              ))}
+            </div>
+          </>
+        )}
+
+        {isPending && isForm && request.schema && (
+          <>
+            <div className="mt-6 mb-2 text-xs font-semibold text-gray-500 uppercase tracking-wider">
+              Form
+            </div>
+            {error && (
+              <div className="mb-3 px-3 py-2 bg-red-900/30 border border-red-800/50 rounded text-red-300 text-xs">
+                {error}
+              </div>
+            )}
+            <SchemaForm
+              key={request.ID}
+              schema={request.schema}
+              submitting={submitting}
+              onSubmit={(data) => send(() => respondWithData(request.ID, data))}
+            />
+          </>
+        )}
 
         {!isPending && request.response && (
           <>
             <div className="mt-6 mb-2 text-xs font-semibold text-gray-500 uppercase tracking-wider">
-              Your Response
+              {request.status === 'timed_out' ? 'Default Response (timed out)' : 'Your Response'}
             </div>
             <div className="bg-green-900/20 border border-green-800/30 rounded-lg p-4 text-green-200 text-sm leading-relaxed whitespace-pre-wrap">
-              {request.response}
+              {isForm ? formatJSON(request.response) : request.response}
             </div>
+            <Attachments attachments={answeredWith} />
           </>
         )}
+
+        <LaterInThread request={request} thread={thread} onSelect={onSelect} />
+
+        {!isPending && <Outbox sourceName={request.source_name} appName={request.app_name} />}
       </div>
 
       {/* Response input */}
-      {isPending && (
+      {isPending && showTextInput && (
         <form onSubmit={handleSubmit} className="px-6 py-4 border-t border-gray-800 bg-gray-900/50">
           {error && (
             <div className="mb-3 px-3 py-2 bg-red-900/30 border border-red-800/50 rounded text-red-300 text-xs">
@@ -98,25 +263,74 @@ export default function RequestDetail({ request, onResponded }: RequestDetailPro
             <textarea
               value={response}
               onChange={(e) => setResponse(e.target.value)}
-              placeholder="Type your response..."
+              placeholder={isChoice ? 'Or type a different answer...' : 'Type your response...'}
               rows={3}
               className="flex-1 bg-gray-800 border border-gray-700 rounded-lg px-4 py-3 text-sm text-gray-200 placeholder-gray-600 focus:outline-none focus:ring-2 focus:ring-blue-500/50 focus:border-blue-500/50 resize-none"
               disabled={submitting}
+              onPaste={(e) => {
+                // Pasted screenshots arrive as files; plain text pastes as usual.
+                if (e.clipboardData.files.length > 0) {
+                  e.preventDefault();
+                  addFiles(e.clipboardData.files);
+                }
+              }}
               onKeyDown={(e) => {
                 if (e.key === 'Enter' && (e.metaKey || e.ctrlKey)) {
                   handleSubmit(e);
                 }
               }}
             />
-            <button
-              type="submit"
-              disabled={submitting || !response.trim()}
-              className="self-end px-5 py-3 bg-blue-600 hover:bg-blue-500 disabled:bg-gray-700 disabled:text-gray-500 text-white text-sm font-medium rounded-lg transition-colors"
-            >
-              {submitting ? 'Sending...' : 'Send'}
-            </button>
+            <div className="self-end flex flex-col gap-2">
+              <input
+                ref={fileInput}
+                type="file"
+                multiple
+                accept="image/png,image/jpeg,image/gif,image/webp,text/*,.log,.diff,.patch,.md,.csv,.json,.pdf"
+                className="hidden"
+                onChange={(e) => {
+                  addFiles(e.target.files);
+                  e.target.value = '';
+                }}
+              />
+              <button
+                type="button"
+                onClick={() => fileInput.current?.click()}
+                disabled={submitting}
+                className="px-5 py-2 border border-gray-700 hover:bg-gray-800 disabled:opacity-50 text-gray-300 text-sm rounded-lg transition-colors"
+              >
+                Attach
+              </button>
+              <button
+                type="submit"
+                disabled={submitting || (!response.trim() && files.length === 0)}
+                className="px-5 py-3 bg-blue-600 hover:bg-blue-500 disabled:bg-gray-700 disabled:text-gray-500 text-white text-sm font-medium rounded-lg transition-colors"
+              >
+                {submitting ? 'Sending...' : 'Send'}
+              </button>
+            </div>
           </div>
-          <p className="mt-2 text-[10px] text-gray-600">Press Cmd+Enter to send</p>
+          {files.length > 0 && (
+            <ul className="mt-2 flex flex-wrap gap-2">
+              {files.map((f, i) => (
+                <li
+                  key={`${f.name}-${i}`}
+                  className="flex items-center gap-2 px-2 py-1 bg-gray-800 border border-gray-700 rounded text-xs text-gray-300"
+                >
+                  <span className="truncate max-w-[12rem]">{f.name || 'pasted image'}</span>
+                  <span className="text-gray-500">{formatSize(f.size)}</span>
+                  <button
+                    type="button"
+                    onClick={() => setFiles((prev) => prev.filter((_, j) => j !== i))}
+                    className="text-gray-500 hover:text-gray-300"
+                    aria-label={`Remove ${f.name}`}
+                  >
+                    ×
+                  </button>
+                </li>
+              ))}
+            </ul>
+          )}
+          <p className="mt-2 text-[10px] text-gray-600">Press Cmd+Enter to send · paste or attach screenshots and logs</p>
         </form>
       )}
     </div>
@@ -0,0 +1,219 @@
+import { useState } from 'react';
+import { JsonSchema } from '../types';
+
+interface SchemaFormProps {
+  schema: JsonSchema;
+  submitting: boolean;
+  onSubmit: (data: Record<string, unknown>) => void;
+}
+
+function primaryType(schema: JsonSchema): string {
+  const t = Array.isArray(schema.type) ? schema.type.find((x) => x !== 'null') : schema.type;
+  return t || 'string';
+}
+
+function initialValue(schema: JsonSchema): unknown {
+  if (schema.default !== undefined) return schema.default;
+  switch (primaryType(schema)) {
+    case 'boolean':
+      return false;
+    case 'object':
+      return Object.fromEntries(
+        Object.entries(schema.properties || {}).map(([k, s]) => [k, initialValue(s)]),
+      );
+    case 'array':
+      return [];
+    default:
+      return undefined;
+  }
+}
+
+// prune drops empty optional fields so the server's "required" check reports
+// them instead of a type error on an empty string.
+function prune(value: unknown): unknown {
+  if (value && typeof value === 'object' && !Array.isArray(value)) {
+    const out: Record<string, unknown> = {};
+    for (const [k, v] of Object.entries(value as Record<string, unknown>)) {
+      const p = prune(v);
+      if (p !== undefined && p !== '') out[k] = p;
+    }
+    return out;
+  }
+  return value;
+}
+
+const inputClass =
+  'w-full bg-gray-800 border border-gray-700 rounded-lg px-3 py-2 text-sm text-gray-200 placeholder-gray-600 focus:outline-none focus:ring-2 focus:ring-blue-500/50 focus:border-blue-500/50';
+
+function Field({
+  name,
+  schema,
+  required,
+  value,
+  onChange,
+}: {
+  name: string;
+  schema: JsonSchema;
+  required: boolean;
+  value: unknown;
+  onChange: (v: unknown) => void;
+}) {
+  const type = primaryType(schema);
+  const label = (
+    <div className="mb-1 text-xs font-medium text-gray-400">
+      {schema.title || name}
+      {required && <span className="ml-1 text-red-400">*</span>}
+    </div>
+  );
+  const help = schema.description && (
+    <div className="mt-1 text-[11px] text-gray-500">{schema.description}</div>
+  );
+
+  if (schema.enum) {
+    return (
+      <label className="block">
+        {label}
+        <select
+          className={inputClass}
+          value={value === undefined ? '' : JSON.stringify(value)}
+          onChange={(e) => onChange(e.target.value === '' ? undefined : JSON.parse(e.target.value))}
+        >
+          <option value="">Select...</option>
+          {schema.enum.map((opt) => (
+            <option key={JSON.stringify(opt)} value={JSON.stringify(opt)}>
+              {String(opt)}
+            </option>
+          ))}
+        </select>
+        {help}
+      </label>
+    );
+  }
+
+  switch (type) {
+    case 'boolean':
+      return (
+        <label className="flex items-start gap-2">
+          <input
+            type="checkbox"
+            className="mt-0.5"
+            checked={Boolean(value)}
+            onChange={(e) => onChange(e.target.checked)}
+          />
+          <div>
+            {label}
+            {help}
+          </div>
+        </label>
+      );
+    case 'number':
+    case 'integer':
+      return (
+        <label className="block">
+          {label}
+          <input
+            type="number"
+            className={inputClass}
+            step={type === 'integer' ? 1 : 'any'}
+            min={schema.minimum}
+            max={schema.maximum}
+            value={value === undefined ? '' : String(value)}
+            onChange={(e) => onChange(e.target.value === '' ? undefined : Number(e.target.value))}
+          />
+          {help}
+        </label>
+      );
+    case 'object':
+      return (
+        <fieldset className="border border-gray-800 rounded-lg p-3">
+          <legend className="px-1 text-xs font-medium text-gray-400">{schema.title || name}</legend>
+          <Fields
+            schema={schema}
+            value={(value as Record<string, unknown>) || {}}
+            onChange={onChange}
+          />
+        </fieldset>
+      );
+    case 'array':
+      return (
+        <label className="block">
+          {label}
+          <textarea
+            className={`${inputClass} resize-none`}
+            rows={3}
+            placeholder="One item per line"
+            value={Array.isArray(value) ? value.join('\n') : ''}
+            onChange={(e) => {
+              const itemType = schema.items ? primaryType(schema.items) : 'string';
+              const items = e.target.value.split('\n').filter((l) => l.trim() !== '');
+              onChange(itemType === 'number' || itemType === 'integer' ? items.map(Number) : items);
+            }}
+          />
+          {help}
+        </label>
+      );
+    default:
+      return (
+        <label className="block">
+          {label}
+          <input
+            type="text"
+            className={inputClass}
+            value={value === undefined ? '' : String(value)}
+            onChange={(e) => onChange(e.target.value)}
+          />
+          {help}
+        </label>
+      );
+  }
+}
+
+function Fields({
+  schema,
+  value,
+  onChange,
+}: {
+  schema: JsonSchema;
+  value: Record<string, unknown>;
+  onChange: (v: Record<string, unknown>) => void;
+}) {
+  return (
+    <div className="flex flex-col gap-4">
+      {Object.entries(schema.properties || {}).map(([name, prop]) => (
+        <Field
+          key={name}
+          name={name}
+          schema={prop}
+          required={(schema.required || []).includes(name)}
+          value={value[name]}
+          onChange={(v) => onChange({ ...value, [name]: v })}
+        />
+      ))}
+    </div>
+  );
+}
+
+export default function SchemaForm({ schema, submitting, onSubmit }: SchemaFormProps) {
+  const [value, setValue] = useState<Record<string, unknown>>(
+    () => initialValue(schema) as Record<string, unknown>,
+  );
+
+  return (
+    <form
+      onSubmit={(e) => {
+        e.preventDefault();
+        onSubmit(prune(value) as Record<string, unknown>);
+      }}
+      className="flex flex-col gap-4"
+    >
+      <Fields schema={schema} value={value} onChange={setValue} />
+      <button
+        type="submit"
+        disabled={submitting}
+        className="self-start px-5 py-2 bg-blue-600 hover:bg-blue-500 disabled:bg-gray-700 disabled:text-gray-500 text-white text-sm font-medium rounded-lg transition-colors"
+      >
+        {submitting ? 'Sending...' : 'Submit'}
+      </button>
+    </form>
+  );
+}
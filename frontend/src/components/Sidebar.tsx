@@ -1,10 +1,18 @@
-import { Request } from '../types';
+import { useEffect, useState } from 'react';
+import { Request, SearchResult } from '../types';
+import { searchRequests } from '../api';
+import { statusStyle } from '../status';
 
 interface SidebarProps {
   requests: Request[];
   selectedId: number | null;
   onSelect: (id: number) => void;
   sourceName: string;
+  hasOlder: boolean;
+  loadingOlder: boolean;
+  onLoadOlder: () => void;
+  total: number;
+  onClearHistory: () => void;
 }
 
 function timeAgo(dateStr: string): string {
@@ -17,7 +25,94 @@ function timeAgo(dateStr: string): string {
   return `${Math.floor(diff / 86400)}d ago`;
 }
 
-export default function Sidebar({ requests, selectedId, onSelect, sourceName }: SidebarProps) {
+// useSearch runs a search once the query has not changed for a moment.
+function useSearch(query: string): { results: SearchResult[] | null; error: boolean } {
+  const [results, setResults] = useState<SearchResult[] | null>(null);
+  const [error, setError] = useState(false);
+
+  useEffect(() => {
+    const q = query.trim();
+    if (!q) {
+      setResults(null);
+      return;
+    }
+    let cancelled = false;
+    const timer = setTimeout(() => {
+      searchRequests(q)
+        .then((r) => {
+          if (cancelled) return;
+          setResults(r);
+          setError(false);
+        })
+        .catch(() => !cancelled && setError(true));
+    }, 250);
+    return () => {
+      cancelled = true;
+      clearTimeout(timer);
+    };
+  }, [query]);
+
+  return { results, error };
+}
+
+function SearchResults({
+  results,
+  selectedId,
+  onSelect,
+}: {
+  results: SearchResult[];
+  selectedId: number | null;
+  onSelect: (id: number) => void;
+}) {
+  if (results.length === 0) {
+    return <div className="px-4 py-8 text-center text-gray-600 text-sm">No matches.</div>;
+  }
+  return (
+    <>
+      {results.map((req) => (
+        <button
+          key={req.ID}
+          onClick={() => onSelect(req.ID)}
+          className={`w-full text-left px-4 py-3 border-b border-gray-800/50 transition-colors ${
+            selectedId === req.ID
+              ? 'bg-blue-600/20 border-l-2 border-l-blue-500'
+              : 'hover:bg-gray-800/50 border-l-2 border-l-transparent'
+          }`}
+        >
+          <div className="flex items-center justify-between mb-1">
+            <span className="text-[10px] font-semibold text-gray-500 uppercase tracking-wider truncate">
+              {req.app_name}
+            </span>
+            <span className="text-[10px] text-gray-600">
+              #{req.ID} · {timeAgo(req.CreatedAt)}
+            </span>
+          </div>
+          <p className="text-sm text-gray-300 truncate">{req.question}</p>
+          {/* The server escapes snippets; only its <mark> tags are HTML. */}
+          <p
+            className="mt-1 text-xs text-gray-500 line-clamp-2 [&_mark]:bg-yellow-500/30 [&_mark]:text-yellow-100 [&_mark]:rounded-sm"
+            dangerouslySetInnerHTML={{ __html: req.snippet }}
+          />
+        </button>
+      ))}
+    </>
+  );
+}
+
+export default function Sidebar({
+  requests,
+  selectedId,
+  onSelect,
+  sourceName,
+  hasOlder,
+  loadingOlder,
+  onLoadOlder,
+  total,
+  onClearHistory,
+}: SidebarProps) {
+  const [query, setQuery] = useState('');
+  const { results, error } = useSearch(query);
+
   const grouped = requests.reduce<Record<string, Request[]>>((acc, req) => {
     if (!acc[req.app_name]) acc[req.app_name] = [];
     acc[req.app_name].push(req);
@@ -33,14 +128,24 @@ export default function Sidebar({ requests, selectedId, onSelect, sourceName }:
         <p className="text-xs text-gray-500 mt-0.5">
           {sourceName ? `Connected to ${sourceName}` : 'Human-in-the-loop assistant'}
         </p>
+        <input
+          type="search"
+          value={query}
+          onChange={(e) => setQuery(e.target.value)}
+          onKeyDown={(e) => e.key === 'Escape' && setQuery('')}
+          placeholder="Search questions and answers..."
+          className="mt-3 w-full bg-gray-800 border border-gray-700 rounded-md px-3 py-1.5 text-sm text-gray-200 placeholder-gray-600 focus:outline-none focus:ring-2 focus:ring-blue-500/50"
+        />
       </div>
       <div className="flex-1 overflow-y-auto">
-        {appNames.length === 0 && (
+        {error && <div className="px-4 py-2 text-xs text-red-400">Search failed</div>}
+        {results && <SearchResults results={results} selectedId={selectedId} onSelect={onSelect} />}
+        {!results && appNames.length === 0 && (
           <div className="px-4 py-8 text-center text-gray-600 text-sm">
             No requests yet. Waiting for incoming questions...
           </div>
         )}
-        {appNames.map((appName) => (
+        {!results && appNames.map((appName) => (
           <div key={appName}>
             <div className="px-4 py-2 text-xs font-semibold text-gray-500 uppercase tracking-wider bg-gray-900/50 sticky top-0">
               {appName}
@@ -57,22 +162,54 @@ export default function Sidebar({ requests, selectedId, onSelect, sourceName }:
               >
                 <div className="flex items-center justify-between mb-1">
                   <span
-                    className={`inline-block px-1.5 py-0.5 rounded text-[10px] font-medium ${
-                      req.status === 'pending'
-                        ? 'bg-amber-500/20 text-amber-400'
-                        : 'bg-green-500/20 text-green-400'
-                    }`}
+                    className={`inline-block px-1.5 py-0.5 rounded text-[10px] font-medium ${statusStyle(req.status).className}`}
                   >
-                    {req.status === 'pending' ? 'PENDING' : 'DONE'}
+                    {statusStyle(req.status).badge}
+                  </span>
+                  <span className="text-[10px] text-gray-600">
+                    {req.parent_id ? `↳ #${req.parent_id} · ` : ''}
+                    {timeAgo(req.CreatedAt)}
                   </span>
-                  <span className="text-[10px] text-gray-600">{timeAgo(req.CreatedAt)}</span>
                 </div>
-                <p className="text-sm text-gray-300 truncate">{req.question}</p>
+                <p
+                  className={`text-sm truncate ${
+                    req.status === 'orphaned' ? 'text-gray-500 italic line-through decoration-gray-600' : 'text-gray-300'
+                  }`}
+                  title={req.status === 'orphaned' ? 'The agent that asked this is no longer running' : undefined}
+                >
+                  {req.question}
+                </p>
               </button>
             ))}
           </div>
         ))}
+        {!results && hasOlder && (
+          <div className="px-4 py-3 text-center">
+            <button
+              type="button"
+              onClick={onLoadOlder}
+              disabled={loadingOlder}
+              className="text-xs text-blue-400 hover:text-blue-300 disabled:text-gray-600"
+            >
+              {loadingOlder ? 'Loading…' : 'Load older requests'}
+            </button>
+            <p className="mt-1 text-[10px] text-gray-600">
+              Showing {requests.length} of {total}
+            </p>
+          </div>
+        )}
       </div>
+      {requests.some((r) => r.status !== 'pending') && (
+        <div className="px-4 py-2 border-t border-gray-800 text-right">
+          <button
+            type="button"
+            onClick={onClearHistory}
+            className="text-[10px] text-gray-500 hover:text-red-400"
+          >
+            Clear history
+          </button>
+        </div>
+      )}
     </aside>
   );
 }
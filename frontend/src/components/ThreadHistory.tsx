@@ -0,0 +1,93 @@
+import { useEffect, useState } from 'react';
+import { Request } from '../types';
+import { fetchThread } from '../api';
+import { statusStyle } from '../status';
+
+interface ThreadProps {
+  request: Request;
+  thread: Request[];
+  onSelect: (id: number) => void;
+}
+
+// useThread loads the conversation a request belongs to. It reloads
+// whenever the request object changes, which App does on every refresh.
+export function useThread(request: Request | null): Request[] {
+  const [thread, setThread] = useState<Request[]>([]);
+
+  useEffect(() => {
+    if (!request?.thread_id) {
+      setThread([]);
+      return;
+    }
+    let cancelled = false;
+    fetchThread(request.thread_id)
+      .then((requests) => {
+        if (!cancelled) setThread(requests);
+      })
+      .catch(() => {
+        if (!cancelled) setThread([]);
+      });
+    return () => {
+      cancelled = true;
+    };
+  }, [request]);
+
+  return thread;
+}
+
+function Exchange({ req, onSelect }: { req: Request; onSelect: (id: number) => void }) {
+  return (
+    <button
+      type="button"
+      onClick={() => onSelect(req.ID)}
+      className="w-full text-left px-4 py-3 rounded-lg border border-gray-800 bg-gray-900/40 hover:bg-gray-800/50 transition-colors"
+    >
+      <div className="flex items-center gap-2 mb-1">
+        <span className="text-[10px] text-gray-600">#{req.ID}</span>
+        <span className={`inline-block px-1.5 py-0.5 rounded text-[10px] font-medium ${statusStyle(req.status).className}`}>
+          {statusStyle(req.status).badge}
+        </span>
+      </div>
+      <p className="text-xs text-gray-400 whitespace-pre-wrap line-clamp-3">{req.question}</p>
+      {req.response && (
+        <p className="mt-1.5 text-xs text-green-300/80 whitespace-pre-wrap line-clamp-3">↳ {req.response}</p>
+      )}
+    </button>
+  );
+}
+
+// EarlierInThread lists the exchanges that led up to a follow-up question.
+export function EarlierInThread({ request, thread, onSelect }: ThreadProps) {
+  const earlier = thread.filter((r) => r.ID < request.ID);
+  if (earlier.length === 0) return null;
+  return (
+    <div className="mb-6">
+      <div className="mb-2 text-xs font-semibold text-gray-500 uppercase tracking-wider">
+        Earlier in this conversation
+      </div>
+      <div className="flex flex-col gap-2">
+        {earlier.map((r) => (
+          <Exchange key={r.ID} req={r} onSelect={onSelect} />
+        ))}
+      </div>
+    </div>
+  );
+}
+
+// LaterInThread lists the follow-ups asked after a request was answered.
+export function LaterInThread({ request, thread, onSelect }: ThreadProps) {
+  const later = thread.filter((r) => r.ID > request.ID);
+  if (later.length === 0) return null;
+  return (
+    <div className="mt-6">
+      <div className="mb-2 text-xs font-semibold text-gray-500 uppercase tracking-wider">
+        Follow-ups
+      </div>
+      <div className="flex flex-col gap-2">
+        {later.map((r) => (
+          <Exchange key={r.ID} req={r} onSelect={onSelect} />
+        ))}
+      </div>
+    </div>
+  );
+}
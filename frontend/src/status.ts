@@ -0,0 +1,19 @@
+interface StatusStyle {
+  label: string;
+  badge: string;
+  className: string;
+}
+
+const STATUS_STYLES: Record<string, StatusStyle> = {
+  pending: { label: 'Awaiting Response', badge: 'PENDING', className: 'bg-amber-500/20 text-amber-400' },
+  responded: { label: 'Responded', badge: 'DONE', className: 'bg-green-500/20 text-green-400' },
+  timed_out: { label: 'Timed Out', badge: 'TIMED OUT', className: 'bg-gray-500/20 text-gray-400' },
+  cancelled: { label: 'Cancelled by Agent', badge: 'CANCELLED', className: 'bg-red-500/20 text-red-400' },
+  orphaned: { label: 'Orphaned', badge: 'ORPHANED', className: 'bg-purple-500/20 text-purple-300' },
+  unread: { label: 'Notification', badge: 'NEW', className: 'bg-blue-500/20 text-blue-400' },
+  acknowledged: { label: 'Acknowledged', badge: 'SEEN', className: 'bg-gray-500/20 text-gray-400' },
+};
+
+export function statusStyle(status: string): StatusStyle {
+  return STATUS_STYLES[status] || { label: status, badge: status.toUpperCase(), className: 'bg-gray-500/20 text-gray-400' };
+}
@@ -1,12 +1,75 @@
+export interface Option {
+  label: string;
+  description?: string;
+}
+
+export interface JsonSchema {
+  type?: string | string[];
+  title?: string;
+  description?: string;
+  properties?: Record<string, JsonSchema>;
+  required?: string[];
+  enum?: unknown[];
+  minimum?: number;
+  maximum?: number;
+  minLength?: number;
+  maxLength?: number;
+  pattern?: string;
+  items?: JsonSchema;
+  default?: unknown;
+}
+
+export interface Attachment {
+  request_id: number;
+  index: number;
+  name: string;
+  mime_type: string;
+  size: number;
+  from_human: boolean;
+}
+
+// RequestPage is one page of GET /api/requests?limit=…
+export interface RequestPage {
+  requests: Request[];
+  next_cursor?: string;
+  total: number;
+}
+
+// SearchResult is a request matching a search. snippet is HTML-escaped
+// by the server, with the matched words wrapped in <mark>.
+export interface SearchResult extends Request {
+  snippet: string;
+}
+
 export interface Request {
   ID: number;
   CreatedAt: string;
   UpdatedAt: string;
   DeletedAt: string | null;
+  uuid: string;
   source_name: string;
   app_name: string;
+  kind: string;
   question: string;
+  options?: Option[];
+  default_option?: string;
+  allow_other: boolean;
+  schema?: JsonSchema;
+  expires_at: string | null;
+  default_response?: string;
   response: string;
   status: string;
   responded_at: string | null;
+  thread_id: number;
+  parent_id?: number;
+  attachments?: Attachment[];
+}
+
+export interface Message {
+  ID: number;
+  CreatedAt: string;
+  source_name: string;
+  app_name: string;
+  body: string;
+  read_at: string | null;
 }
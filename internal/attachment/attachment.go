@@ -0,0 +1,308 @@
+// Package attachment stores the files sent along with requests, such as
+// screenshots and diffs, and decides what may be attached: only an
+// allowlist of content types, within size limits. Files live under the
+// data directory, or in the database itself on PostgreSQL, which hosts
+// with data directories of their own may share.
+package attachment
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxSize is the largest single attachment.
+	MaxSize = 10 << 20
+	// MaxTotal caps the attachments of one request together.
+	MaxTotal = 25 << 20
+	// MaxCount is the most attachments one request may carry.
+	MaxCount = 10
+)
+
+// ErrInvalid wraps every rejection by Validate.
+var ErrInvalid = errors.New("invalid attachment")
+
+// allowed maps the content types that may be attached to the extension
+// given to unnamed files. SVG, HTML and other formats that can carry
+// scripts are left out, since the UI serves attachments from its own
+// origin.
+var allowed = map[string]string{
+	"image/png":        ".png",
+	"image/jpeg":       ".jpg",
+	"image/gif":        ".gif",
+	"image/webp":       ".webp",
+	"text/plain":       ".txt",
+	"text/markdown":    ".md",
+	"text/csv":         ".csv",
+	"text/x-diff":      ".diff",
+	"text/x-patch":     ".patch",
+	"text/x-log":       ".log",
+	"application/json": ".json",
+	"application/pdf":  ".pdf",
+}
+
+// Allowed reports whether files of mimeType may be attached.
+func Allowed(mimeType string) bool {
+	_, ok := allowed[Normalize(mimeType)]
+	return ok
+}
+
+// Normalize lowercases mimeType and drops its parameters.
+func Normalize(mimeType string) string {
+	if t, _, err := mime.ParseMediaType(mimeType); err == nil {
+		return t
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// IsImage reports whether mimeType is one of the allowed image types.
+func IsImage(mimeType string) bool {
+	return strings.HasPrefix(Normalize(mimeType), "image/") && Allowed(mimeType)
+}
+
+// DetectType guesses the content type of a file that arrived without a
+// useful one, as browsers send for logs and diffs: images by their
+// content, anything else by the extension of name, then by content.
+func DetectType(name string, data []byte) string {
+	sniffed := Normalize(http.DetectContentType(data))
+	if IsImage(sniffed) {
+		return sniffed
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for t, e := range allowed {
+		if e == ext {
+			return t
+		}
+	}
+	if t := Normalize(mime.TypeByExtension(ext)); ext != "" && Allowed(t) {
+		return t
+	}
+	return sniffed
+}
+
+// Validate checks atts against the allowlist and the size limits. Images
+// must also look like what they claim to be.
+func Validate(atts []db.Attachment) error {
+	if len(atts) > MaxCount {
+		return fmt.Errorf("%w: at most %d attachments are allowed", ErrInvalid, MaxCount)
+	}
+	var total int
+	for i, a := range atts {
+		name := a.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		if !Allowed(a.MIMEType) {
+			return fmt.Errorf("%w: %s has unsupported type %q", ErrInvalid, name, a.MIMEType)
+		}
+		if len(a.Data) == 0 {
+			return fmt.Errorf("%w: %s is empty", ErrInvalid, name)
+		}
+		if len(a.Data) > MaxSize {
+			return fmt.Errorf("%w: %s is larger than %d MiB", ErrInvalid, name, MaxSize>>20)
+		}
+		if t := Normalize(a.MIMEType); strings.HasPrefix(t, "image/") && http.DetectContentType(a.Data) != t {
+			return fmt.Errorf("%w: %s is not a valid %s image", ErrInvalid, name, t)
+		}
+		total += len(a.Data)
+	}
+	if total > MaxTotal {
+		return fmt.Errorf("%w: attachments are larger than %d MiB together", ErrInvalid, MaxTotal>>20)
+	}
+	return nil
+}
+
+// Save writes the contents of atts to disk, or to the database on
+// PostgreSQL, and stores them as attachments of request requestID,
+// numbered after the ones it already has. The returned attachments carry
+// their stored metadata but no Data. On error nothing is left behind.
+// Numbers are unique per request, so of two saves racing for the same
+// request one fails; callers that may race run Save in a transaction with
+// the change that claims the request.
+func Save(database *gorm.DB, requestID uint, atts []db.Attachment) ([]db.Attachment, error) {
+	var existing int64
+	if err := database.Model(&db.Attachment{}).Where("request_id = ?", requestID).Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to count attachments: %w", err)
+	}
+
+	if !inDatabase(database) {
+		dir := filepath.Join(config.AttachmentsDir(), fmt.Sprint(requestID))
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+
+	saved := make([]db.Attachment, 0, len(atts))
+	for i, a := range atts {
+		stored, err := save(database, requestID, int(existing)+i, a)
+		if err != nil {
+			Remove(database, saved)
+			return nil, err
+		}
+		saved = append(saved, stored)
+	}
+	return saved, nil
+}
+
+func save(database *gorm.DB, requestID uint, index int, a db.Attachment) (db.Attachment, error) {
+	a.RequestID = requestID
+	a.Index = index
+	a.MIMEType = Normalize(a.MIMEType)
+	a.Name = cleanName(a.Name, a.Index, a.MIMEType)
+	a.Size = int64(len(a.Data))
+	data := a.Data
+	a.Data = nil
+
+	if inDatabase(database) {
+		if err := database.Create(&a).Error; err != nil {
+			return a, fmt.Errorf("failed to store attachment: %w", err)
+		}
+		if err := database.Exec("UPDATE attachments SET content = ? WHERE id = ?", data, a.ID).Error; err != nil {
+			database.Unscoped().Delete(&db.Attachment{}, a.ID)
+			return a, fmt.Errorf("failed to store attachment: %w", err)
+		}
+		return a, nil
+	}
+
+	// A unique name keeps a save that loses a race from touching the
+	// files of the one that won.
+	a.Path = fmt.Sprintf("%d/%d-%s", requestID, a.Index, uuid.NewString())
+	if err := writeFile(FilePath(&a), data); err != nil {
+		return a, err
+	}
+	if err := database.Create(&a).Error; err != nil {
+		os.Remove(FilePath(&a))
+		return a, fmt.Errorf("failed to store attachment: %w", err)
+	}
+	return a, nil
+}
+
+// inDatabase reports whether attachments stored in database keep their
+// content in it rather than on disk.
+func inDatabase(database *gorm.DB) bool {
+	return database.Dialector.Name() == "postgres"
+}
+
+// RemoveAll deletes the attachments of request requestID, files and rows.
+func RemoveAll(database *gorm.DB, requestID uint) error {
+	if err := database.Unscoped().Where("request_id = ?", requestID).Delete(&db.Attachment{}).Error; err != nil {
+		return fmt.Errorf("failed to delete attachments of request %d: %w", requestID, err)
+	}
+	return RemoveFiles(requestID)
+}
+
+// RemoveFiles deletes the attachment files of request requestID, for
+// when its rows are already gone.
+func RemoveFiles(requestID uint) error {
+	if err := os.RemoveAll(filepath.Join(config.AttachmentsDir(), fmt.Sprint(requestID))); err != nil {
+		return fmt.Errorf("failed to delete attachments of request %d: %w", requestID, err)
+	}
+	return nil
+}
+
+// Remove deletes the given stored attachments, files and rows.
+func Remove(database *gorm.DB, atts []db.Attachment) {
+	Discard(atts)
+	for _, a := range atts {
+		database.Unscoped().Delete(&db.Attachment{}, a.ID)
+	}
+}
+
+// Discard deletes the files of atts, whose rows were rolled back with the
+// transaction that stored them. Their ids may since have been reused, so
+// no rows are touched.
+func Discard(atts []db.Attachment) {
+	for _, a := range atts {
+		if a.Path != "" {
+			os.Remove(FilePath(&a))
+		}
+	}
+}
+
+// Open opens the stored content of a, read from database if it is kept
+// there.
+func Open(database *gorm.DB, a *db.Attachment) (io.ReadSeekCloser, error) {
+	if a.Path != "" {
+		return os.Open(FilePath(a))
+	}
+	data, err := Read(database, a)
+	if err != nil {
+		return nil, err
+	}
+	return nopCloser{bytes.NewReader(data)}, nil
+}
+
+// Read returns the stored content of a.
+func Read(database *gorm.DB, a *db.Attachment) ([]byte, error) {
+	if a.Path != "" {
+		return os.ReadFile(FilePath(a))
+	}
+	var rows []struct{ Content []byte }
+	if err := database.Raw("SELECT content FROM attachments WHERE id = ?", a.ID).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read attachment %d: %w", a.ID, err)
+	}
+	if len(rows) == 0 || rows[0].Content == nil {
+		return nil, fmt.Errorf("attachment %d: %w", a.ID, os.ErrNotExist)
+	}
+	return rows[0].Content, nil
+}
+
+type nopCloser struct{ *bytes.Reader }
+
+func (nopCloser) Close() error { return nil }
+
+// FilePath is where the content of a is stored on disk, for attachments
+// that have a Path.
+func FilePath(a *db.Attachment) string {
+	return filepath.Join(config.AttachmentsDir(), filepath.FromSlash(a.Path))
+}
+
+// cleanName makes name safe to show and to offer as a download name.
+func cleanName(name string, index int, mimeType string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	if r := []rune(name); len(r) > 200 {
+		name = string(r[:200])
+	}
+	if name == "" || name == "." || name == "/" {
+		name = fmt.Sprintf("attachment-%d%s", index+1, allowed[mimeType])
+	}
+	return name
+}
+
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".attachment-*")
+	if err != nil {
+		return fmt.Errorf("failed to write attachment: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write attachment: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write attachment: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write attachment: %w", err)
+	}
+	return nil
+}
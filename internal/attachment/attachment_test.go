@@ -0,0 +1,212 @@
+package attachment
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// png is the smallest valid PNG signature http.DetectContentType accepts.
+var png = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	old := config.DataDir
+	config.DataDir = t.TempDir()
+	t.Cleanup(func() { config.DataDir = old })
+
+	d, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	if err := db.Migrate(d); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return d
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		atts []db.Attachment
+		ok   bool
+	}{
+		{"png", []db.Attachment{{MIMEType: "image/png", Data: png}}, true},
+		{"diff with charset", []db.Attachment{{MIMEType: "text/x-diff; charset=utf-8", Data: []byte("+a\n")}}, true},
+		{"html", []db.Attachment{{MIMEType: "text/html", Data: []byte("<script>")}}, false},
+		{"svg", []db.Attachment{{MIMEType: "image/svg+xml", Data: []byte("<svg/>")}}, false},
+		{"fake png", []db.Attachment{{MIMEType: "image/png", Data: []byte("<html>")}}, false},
+		{"empty", []db.Attachment{{MIMEType: "text/plain"}}, false},
+		{"too large", []db.Attachment{{MIMEType: "text/plain", Data: make([]byte, MaxSize+1)}}, false},
+		{"too large together", []db.Attachment{
+			{MIMEType: "text/plain", Data: make([]byte, MaxSize)},
+			{MIMEType: "text/plain", Data: make([]byte, MaxSize)},
+			{MIMEType: "text/plain", Data: make([]byte, MaxSize)},
+		}, false},
+		{"too many", make([]db.Attachment, MaxCount+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.atts)
+			if tt.ok && err != nil {
+				t.Errorf("expected valid, got %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalid) {
+				t.Errorf("expected ErrInvalid, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDetectType(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"screenshot", png, "image/png"},
+		{"build.log", []byte("ok\n"), "text/x-log"},
+		{"fix.diff", []byte("-a\n+b\n"), "text/x-diff"},
+		{"notes.md", []byte("# hi\n"), "text/markdown"},
+		{"shot.txt", png, "image/png"},
+		{"blob", []byte{0, 1, 2}, "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		if got := DetectType(tt.name, tt.data); got != tt.want {
+			t.Errorf("DetectType(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSaveOpenAndRemove(t *testing.T) {
+	d := setupTestDB(t)
+
+	saved, err := Save(d, 7, []db.Attachment{
+		{Name: "../../etc/shot.png", MIMEType: "image/png", Data: png},
+		{MIMEType: "text/x-diff", Data: []byte("-old\n+new\n")},
+	})
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if saved[0].Name != "shot.png" || saved[0].Index != 0 || saved[0].Data != nil {
+		t.Errorf("unexpected first attachment: %+v", saved[0])
+	}
+	if saved[1].Name != "attachment-2.diff" || saved[1].Index != 1 || saved[1].Size != 10 {
+		t.Errorf("unexpected second attachment: %+v", saved[1])
+	}
+
+	// Later attachments are numbered after the existing ones.
+	more, err := Save(d, 7, []db.Attachment{{Name: "log.txt", MIMEType: "text/plain", Data: []byte("x")}})
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if more[0].Index != 2 {
+		t.Errorf("expected index 2, got %d", more[0].Index)
+	}
+
+	f, err := Open(d, &saved[0])
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	got, _ := io.ReadAll(f)
+	f.Close()
+	if !bytes.Equal(got, png) {
+		t.Errorf("stored content differs: %q", got)
+	}
+	if info, err := os.Stat(FilePath(&saved[0])); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected a private file, got %v %v", info, err)
+	}
+
+	if err := RemoveAll(d, 7); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	if _, err := Open(d, &saved[0]); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the file to be gone, got %v", err)
+	}
+	var count int64
+	d.Model(&db.Attachment{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected no attachment rows, got %d", count)
+	}
+}
+
+func TestOpenContentInDatabase(t *testing.T) {
+	d := setupTestDB(t)
+
+	a := db.Attachment{RequestID: 7, Name: "shot.png", MIMEType: "image/png", Size: int64(len(png))}
+	if err := d.Create(&a).Error; err != nil {
+		t.Fatalf("failed to create attachment: %v", err)
+	}
+	if _, err := Read(d, &a); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected missing content to read as not existing, got %v", err)
+	}
+	if err := d.Exec("UPDATE attachments SET content = ? WHERE id = ?", png, a.ID).Error; err != nil {
+		t.Fatalf("failed to store content: %v", err)
+	}
+
+	f, err := Open(d, &a)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	got, _ := io.ReadAll(f)
+	f.Close()
+	if !bytes.Equal(got, png) {
+		t.Errorf("stored content differs: %q", got)
+	}
+
+	// Discarding touches files only, and this one has none.
+	if err := os.MkdirAll(config.AttachmentsDir(), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	Discard([]db.Attachment{a})
+	if _, err := os.Stat(config.AttachmentsDir()); err != nil {
+		t.Errorf("expected the attachments directory to stay, got %v", err)
+	}
+}
+
+// TestSaveInPostgres needs RISHVAN_TEST_DATABASE_URL set to an empty
+// PostgreSQL database; its tables are dropped afterwards.
+func TestSaveInPostgres(t *testing.T) {
+	url := os.Getenv("RISHVAN_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("RISHVAN_TEST_DATABASE_URL is not set")
+	}
+	old := config.DataDir
+	config.DataDir = t.TempDir()
+	t.Cleanup(func() { config.DataDir = old })
+	d, err := db.Open(url)
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	if err := db.Migrate(d); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		d.Migrator().DropTable(&db.Attachment{}, &db.Message{}, &db.Request{}, "schema_migrations")
+	})
+
+	saved, err := Save(d, 7, []db.Attachment{{Name: "shot.png", MIMEType: "image/png", Data: png}})
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if saved[0].Path != "" {
+		t.Errorf("expected the content to be kept in the database, got path %q", saved[0].Path)
+	}
+	if _, err := os.Stat(config.AttachmentsDir()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected nothing on disk, got %v", err)
+	}
+	got, err := Read(d, &saved[0])
+	if err != nil || !bytes.Equal(got, png) {
+		t.Errorf("stored content differs: %q (%v)", got, err)
+	}
+}
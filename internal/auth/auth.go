@@ -0,0 +1,189 @@
+// Package auth manages the per-install secret that guards the primary's
+// HTTP API, and the short-lived one-time tickets that hand it to a browser.
+//
+// The token lives in the data directory, readable only by its owner, so
+// every rishvan-mcp process sharing that directory (primary, secondaries,
+// CLI) can authenticate, while other users and web pages cannot.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+)
+
+const (
+	tokenFile = "token"
+
+	// TicketTTL is how long a login ticket stays redeemable.
+	TicketTTL = 2 * time.Minute
+)
+
+var (
+	mu          sync.Mutex
+	cachedPath  string
+	cachedToken string
+
+	ticketMu sync.Mutex
+	tickets  = map[string]time.Time{}
+)
+
+// Token returns the install's API token, creating it on first use.
+func Token() (string, error) {
+	path := filepath.Join(config.DataDir, tokenFile)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if cachedPath == path && cachedToken != "" {
+		return cachedToken, nil
+	}
+
+	token, err := readToken(path)
+	if errors.Is(err, os.ErrNotExist) {
+		token, err = createToken(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	cachedPath, cachedToken = path, token
+	return token, nil
+}
+
+// Valid reports whether presented matches the install's token.
+func Valid(presented string) bool {
+	if presented == "" {
+		return false
+	}
+	token, err := Token()
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
+
+// CSRFToken derives the anti-CSRF token embedded in the web UI from the
+// API token, so it is stable across restarts and needs no storage of its
+// own.
+func CSRFToken() (string, error) {
+	token, err := Token()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte("rishvan-mcp csrf"))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// ValidCSRF reports whether presented matches CSRFToken.
+func ValidCSRF(presented string) bool {
+	if presented == "" {
+		return false
+	}
+	want, err := CSRFToken()
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(presented), []byte(want))
+}
+
+func readToken(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// createToken writes a new token to path unless another process wins the
+// race, in which case that process's token is returned. The file is
+// written in full before it appears under its final name, so readers
+// never see a partial token.
+func createToken(path string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("failed to create data directory: %w", err)
+	}
+	token, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".token-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to write token: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write token: %w", err)
+	}
+	if _, err := tmp.WriteString(token + "\n"); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write token: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write token: %w", err)
+	}
+
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return readToken(path)
+		}
+		return "", fmt.Errorf("failed to write token: %w", err)
+	}
+	return token, nil
+}
+
+// NewTicket returns a random single-use ticket that Redeem accepts for
+// TicketTTL.
+func NewTicket() (string, error) {
+	ticket, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+
+	ticketMu.Lock()
+	defer ticketMu.Unlock()
+	now := time.Now()
+	for t, expires := range tickets {
+		if now.After(expires) {
+			delete(tickets, t)
+		}
+	}
+	tickets[ticket] = now.Add(TicketTTL)
+	return ticket, nil
+}
+
+// Redeem consumes ticket and reports whether it was valid and unexpired.
+func Redeem(ticket string) bool {
+	ticketMu.Lock()
+	defer ticketMu.Unlock()
+	expires, ok := tickets[ticket]
+	if !ok {
+		return false
+	}
+	delete(tickets, ticket)
+	return time.Now().Before(expires)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
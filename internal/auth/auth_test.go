@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+)
+
+func useDataDir(t *testing.T) string {
+	t.Helper()
+	old := config.DataDir
+	config.DataDir = t.TempDir()
+	t.Cleanup(func() { config.DataDir = old })
+	return config.DataDir
+}
+
+func TestTokenIsCreatedOnceAndPersisted(t *testing.T) {
+	dir := useDataDir(t)
+
+	first, err := Token()
+	if err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+	if len(first) != 64 {
+		t.Errorf("expected a 64-char hex token, got %q", first)
+	}
+
+	// Drop the cache to simulate another process reading the file.
+	mu.Lock()
+	cachedPath, cachedToken = "", ""
+	mu.Unlock()
+
+	second, err := Token()
+	if err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+	if first != second {
+		t.Error("expected the persisted token to be reused")
+	}
+
+	info, err := os.Stat(filepath.Join(dir, tokenFile))
+	if err != nil {
+		t.Fatalf("token file missing: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Errorf("expected token file mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestTokenFollowsDataDir(t *testing.T) {
+	useDataDir(t)
+	a, _ := Token()
+	useDataDir(t)
+	b, _ := Token()
+	if a == b {
+		t.Error("expected different data dirs to have different tokens")
+	}
+}
+
+func TestValid(t *testing.T) {
+	useDataDir(t)
+	token, _ := Token()
+
+	if !Valid(token) {
+		t.Error("expected the token to be valid")
+	}
+	for _, bad := range []string{"", "nope", token + "x"} {
+		if Valid(bad) {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestTicketsAreSingleUse(t *testing.T) {
+	ticket, err := NewTicket()
+	if err != nil {
+		t.Fatalf("NewTicket failed: %v", err)
+	}
+	if !Redeem(ticket) {
+		t.Fatal("expected a fresh ticket to be redeemable")
+	}
+	if Redeem(ticket) {
+		t.Error("expected a ticket to work only once")
+	}
+	if Redeem("unknown") {
+		t.Error("expected an unknown ticket to be rejected")
+	}
+}
+
+func TestExpiredTicketIsRejected(t *testing.T) {
+	ticket, _ := NewTicket()
+	ticketMu.Lock()
+	tickets[ticket] = time.Now().Add(-time.Second)
+	ticketMu.Unlock()
+
+	if Redeem(ticket) {
+		t.Error("expected an expired ticket to be rejected")
+	}
+}
+
+func TestCSRFToken(t *testing.T) {
+	useDataDir(t)
+	token, _ := Token()
+	csrf, err := CSRFToken()
+	if err != nil {
+		t.Fatalf("CSRFToken failed: %v", err)
+	}
+	if csrf == token {
+		t.Error("expected the CSRF token to differ from the API token")
+	}
+	if !ValidCSRF(csrf) {
+		t.Error("expected the CSRF token to be valid")
+	}
+	if ValidCSRF("") || ValidCSRF(token) {
+		t.Error("expected other values to be rejected")
+	}
+}
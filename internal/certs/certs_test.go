@@ -0,0 +1,67 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestEnsureSelfSigned(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls", "cert.pem"), filepath.Join(dir, "tls", "key.pem")
+
+	if err := EnsureSelfSigned(certFile, keyFile, []string{"localhost", "127.0.0.1"}); err != nil {
+		t.Fatalf("EnsureSelfSigned failed: %v", err)
+	}
+	if _, err := ServerConfig(certFile, keyFile); err != nil {
+		t.Fatalf("generated key pair does not load: %v", err)
+	}
+	if info, err := os.Stat(keyFile); err != nil {
+		t.Fatalf("key missing: %v", err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Errorf("expected key mode 0600, got %v", info.Mode().Perm())
+	}
+
+	cert, err := readCert(certFile)
+	if err != nil {
+		t.Fatalf("readCert failed: %v", err)
+	}
+	for _, host := range []string{"localhost", "127.0.0.1"} {
+		if _, err := cert.Verify(x509.VerifyOptions{DNSName: host, Roots: Pool(certFile)}); err != nil {
+			t.Errorf("expected the pinned certificate to verify for %s: %v", host, err)
+		}
+	}
+}
+
+func TestEnsureSelfSignedReusesCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	EnsureSelfSigned(certFile, keyFile, []string{"localhost"})
+	first, _ := os.ReadFile(certFile)
+	EnsureSelfSigned(certFile, keyFile, []string{"localhost"})
+	second, _ := os.ReadFile(certFile)
+	if !bytes.Equal(first, second) {
+		t.Error("expected an existing certificate to be reused")
+	}
+
+	// A new bind address must be covered, so the certificate is replaced.
+	EnsureSelfSigned(certFile, keyFile, []string{"localhost", "10.1.2.3"})
+	third, _ := os.ReadFile(certFile)
+	if bytes.Equal(second, third) {
+		t.Error("expected the certificate to be regenerated for a new host")
+	}
+	cert, _ := readCert(certFile)
+	if err := cert.VerifyHostname("10.1.2.3"); err != nil {
+		t.Errorf("expected the new host to be covered: %v", err)
+	}
+}
+
+func TestPoolSkipsMissingFiles(t *testing.T) {
+	if Pool(filepath.Join(t.TempDir(), "missing.pem")) == nil {
+		t.Error("expected a pool even when files are missing")
+	}
+}
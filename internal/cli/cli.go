@@ -0,0 +1,116 @@
+// Package cli implements the rishvan-mcp command line: the stdio MCP
+// server plus commands for inspecting and answering requests from a
+// terminal.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"serve", "run the stdio MCP server (default)", runServe},
+		{"list", "list requests", runList},
+		{"show", "show one request", runShow},
+		{"respond", "answer a pending request through the running primary", runRespond},
+		{"ack", "acknowledge a notification through the running primary", runAck},
+		{"tui", "answer requests interactively in the terminal", runTUI},
+		{"open", "open the web UI with a one-time login link", runOpen},
+		{"purge", "delete old answered requests", runPurge},
+		{"export", "write request history as JSONL, CSV or Markdown", runExport},
+		{"import", "add requests from a JSONL or CSV export", runImport},
+		{"version", "print build information", runVersion},
+	}
+}
+
+// Run executes the command named by args[0] and returns the process exit
+// code. Without a command, or when args start with a flag, it runs serve
+// so existing "rishvan-mcp --source x" configurations keep working.
+func Run(args []string) int {
+	if err := config.ApplyEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return exit(runServe(args))
+	}
+	if args[0] == "help" {
+		usage(os.Stdout)
+		return 0
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return exit(cmd.run(args[1:]))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "error: unknown command %q\n\n", args[0])
+	usage(os.Stderr)
+	return 2
+}
+
+func exit(err error) int {
+	if err == nil {
+		return 0
+	}
+	if err == flag.ErrHelp {
+		return 2
+	}
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	return 1
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: rishvan-mcp <command> [flags] [args]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun 'rishvan-mcp <command> --help' for the flags of a command.\n")
+}
+
+// newFlagSet returns a flag set for a command with the shared config flags
+// (--port, --bind, --data-dir, --db-path) already registered.
+func newFlagSet(name, usageLine string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: rishvan-mcp %s\n\nflags:\n", usageLine)
+		fs.PrintDefaults()
+	}
+	config.RegisterFlags(fs)
+	return fs
+}
+
+// parseArgs parses flags that may appear before, between or after the
+// positional arguments and returns the positional ones.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if err := config.Load(); err != nil {
+		return nil, err
+	}
+	return positional, nil
+}
@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestParseArgsInterspersedFlags(t *testing.T) {
+	t.Setenv("RISHVAN_DATA_DIR", t.TempDir())
+
+	fs := flag.NewFlagSet("show", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "")
+	positional, err := parseArgs(fs, []string{"42", "--json", "extra"})
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if !*asJSON {
+		t.Error("expected --json after the id to be parsed")
+	}
+	if len(positional) != 2 || positional[0] != "42" || positional[1] != "extra" {
+		t.Errorf("unexpected positional args %v", positional)
+	}
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/export"
+	"github.com/tejzpr/rishvan-mcp/internal/timeutil"
+)
+
+func runExport(args []string) error {
+	fs := newFlagSet("export", "export [--format jsonl|csv|md] [--source S] [--app A] [--since T] [--until T] [-o FILE]")
+	format := fs.String("format", "", "jsonl, csv or md (default: from the -o extension, else jsonl)")
+	source := fs.String("source", "", "only requests from this source")
+	app := fs.String("app", "", "only requests for this app")
+	since := fs.String("since", "", "only requests created at or after this time (RFC 3339 or YYYY-MM-DD)")
+	until := fs.String("until", "", "only requests created before this time (RFC 3339 or YYYY-MM-DD)")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *format == "" {
+		*format = formatFromPath(*output)
+	}
+	if !export.ValidFormat(*format) {
+		return fmt.Errorf("unknown format %q (want %s)", *format, strings.Join(export.Formats, ", "))
+	}
+	filter := export.Filter{SourceName: *source, AppName: *app}
+	var err error
+	if filter.Since, err = timeutil.Parse(*since); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if filter.Until, err = timeutil.Parse(*until); err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+
+	database, err := db.InitReadOnly()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if *output == "" {
+		return export.Write(database, os.Stdout, *format, filter)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	if err := export.Write(database, f, *format, filter); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func runImport(args []string) error {
+	fs := newFlagSet("import", "import [--format jsonl|csv] <file|->")
+	format := fs.String("format", "", "jsonl or csv (default: from the file extension, else jsonl)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one file to import, or - for stdin")
+	}
+	path := positional[0]
+	if *format == "" {
+		*format = formatFromPath(path)
+	}
+
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open import file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	records, err := export.Read(r, *format)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	database, err := db.InitWithoutMigrating()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	res, err := export.Import(database, records)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d request(s), skipped %d already present\n", res.Imported, res.Skipped)
+	return nil
+}
+
+// formatFromPath guesses an export format from a file name, falling back
+// to JSON Lines.
+func formatFromPath(path string) string {
+	switch ext := strings.TrimPrefix(filepath.Ext(path), "."); ext {
+	case export.CSV, export.Markdown:
+		return ext
+	case "markdown":
+		return export.Markdown
+	}
+	return export.JSONL
+}
@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/attachment"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/store"
+	"github.com/tejzpr/rishvan-mcp/internal/textutil"
+)
+
+func runList(args []string) error {
+	fs := newFlagSet("list", "list [--source S] [--app A] [--status S] [--limit N] [--json]")
+	source := fs.String("source", "", "only requests from this source")
+	app := fs.String("app", "", "only requests for this app")
+	status := fs.String("status", "", "only requests with this status (pending, responded, timed_out, cancelled, orphaned)")
+	limit := fs.Int("limit", 50, "maximum number of requests to print, 0 for all")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	if _, err := db.InitReadOnly(); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+
+	q := store.Query{Limit: *limit}
+	if *source != "" {
+		q.SourceNames = []string{*source}
+	}
+	if *app != "" {
+		q.AppNames = []string{*app}
+	}
+	if *status != "" {
+		q.Statuses = []string{*status}
+	}
+	requests, err := store.Get().List(q)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(requests)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tSOURCE\tAPP\tCREATED\tQUESTION")
+	for _, r := range requests {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			r.ID, r.Status, r.SourceName, r.AppName, r.CreatedAt.Local().Format("2006-01-02 15:04"), textutil.OneLine(r.Question, 60))
+	}
+	return tw.Flush()
+}
+
+func runShow(args []string) error {
+	fs := newFlagSet("show", "show <id> [--json]")
+	asJSON := fs.Bool("json", false, "print JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one request id")
+	}
+	id, err := strconv.ParseUint(positional[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid id %q", positional[0])
+	}
+
+	if _, err := db.InitReadOnly(); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	req, err := store.Get().Get(uint(id))
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(req)
+	}
+
+	fmt.Printf("Request #%d (%s)\n", req.ID, req.Status)
+	fmt.Printf("Source:   %s\n", req.SourceName)
+	fmt.Printf("App:      %s\n", req.AppName)
+	fmt.Printf("Kind:     %s\n", req.Kind)
+	if req.ParentID != nil {
+		fmt.Printf("Thread:   #%d, follow-up to #%d\n", req.ThreadID, *req.ParentID)
+	}
+	fmt.Printf("Created:  %s\n", req.CreatedAt.Local().Format(time.RFC1123))
+	if req.ExpiresAt != nil {
+		fmt.Printf("Expires:  %s\n", req.ExpiresAt.Local().Format(time.RFC1123))
+	}
+	label := "Question"
+	if req.Kind == "notification" {
+		label = "Message"
+	}
+	fmt.Printf("\n%s:\n%s\n", label, textutil.Indent(req.Question))
+	for i, opt := range req.Options {
+		line := fmt.Sprintf("  %d. %s", i+1, opt.Label)
+		if opt.Description != "" {
+			line += " - " + opt.Description
+		}
+		if opt.Label == req.DefaultOption {
+			line += " (recommended)"
+		}
+		fmt.Println(line)
+	}
+	if len(req.Schema) > 0 {
+		fmt.Printf("\nSchema:\n%s\n", textutil.Indent(string(req.Schema)))
+	}
+	if len(req.Attachments) > 0 {
+		fmt.Printf("\nAttachments:\n")
+		for _, a := range req.Attachments {
+			from := ""
+			if a.FromHuman {
+				from = ", with the response"
+			}
+			where := "stored in the database"
+			if a.Path != "" {
+				where = attachment.FilePath(&a)
+			}
+			fmt.Printf("  %s (%s, %d bytes%s)\n    %s\n", a.Name, a.MIMEType, a.Size, from, where)
+		}
+	}
+	if req.Response != "" {
+		fmt.Printf("\nResponse")
+		if req.RespondedAt != nil {
+			fmt.Printf(" (%s)", req.RespondedAt.Local().Format(time.RFC1123))
+		}
+		fmt.Printf(":\n%s\n", textutil.Indent(req.Response))
+	}
+	return nil
+}
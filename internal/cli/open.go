@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/tejzpr/rishvan-mcp/internal/browser"
+	"github.com/tejzpr/rishvan-mcp/internal/webserver"
+)
+
+func runOpen(args []string) error {
+	fs := newFlagSet("open", "open [--no-browser]")
+	noBrowser := fs.Bool("no-browser", false, "only print the login link")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	if !webserver.IsRunning() {
+		return fmt.Errorf("no rishvan-mcp primary is running at %s", webserver.PrimaryURL())
+	}
+	url, err := webserver.RemoteLoginURL()
+	if err != nil {
+		return err
+	}
+	fmt.Println(url)
+	if !*noBrowser {
+		_ = browser.Open(url)
+	}
+	return nil
+}
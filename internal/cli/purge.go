@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/manager"
+	"github.com/tejzpr/rishvan-mcp/internal/store"
+)
+
+func runPurge(args []string) error {
+	fs := newFlagSet("purge", "purge --older-than <age> [--status S] [--dry-run]")
+	olderThan := fs.String("older-than", "", "delete requests created longer ago than this, e.g. 30d, 2w or 12h (required)")
+	status := fs.String("status", "", "only delete requests with this status")
+	dryRun := fs.Bool("dry-run", false, "only report how many requests would be deleted")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *olderThan == "" {
+		fs.Usage()
+		return fmt.Errorf("--older-than is required")
+	}
+	age, err := config.ParseAge(*olderThan)
+	if err != nil {
+		return err
+	}
+	if *status == "pending" {
+		return fmt.Errorf("pending requests cannot be purged")
+	}
+
+	if _, err := db.InitWithoutMigrating(); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Pending requests still have an agent waiting on them.
+	q := store.Query{ExcludeStatuses: []string{"pending"}, Until: time.Now().Add(-age), Oldest: true}
+	if *status != "" {
+		q.Statuses = []string{*status}
+	}
+	ids, err := store.Get().IDs(q)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		fmt.Printf("Would delete %d request(s)\n", len(ids))
+		return nil
+	}
+
+	deleted, err := manager.Instance.Delete(ids)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d request(s)\n", len(deleted))
+	return nil
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tejzpr/rishvan-mcp/internal/attachment"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/webserver"
+)
+
+func runRespond(args []string) error {
+	fs := newFlagSet("respond", `respond <id> <text|-> [--attach <file>]...`)
+	var files []string
+	fs.Func("attach", "attach a file or image to the response (repeatable)", func(path string) error {
+		files = append(files, path)
+		return nil
+	})
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 1 && len(files) > 0 {
+		// The attachments alone are the answer.
+		positional = append(positional, "")
+	}
+	if len(positional) != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected a request id and the response text (use - to read it from stdin)")
+	}
+	id, err := strconv.ParseUint(positional[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid id %q", positional[0])
+	}
+
+	text := positional[1]
+	if text == "-" {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read response from stdin: %w", err)
+		}
+		text = strings.TrimRight(string(b), "\n")
+	}
+	if text == "" && len(files) == 0 {
+		return fmt.Errorf("response cannot be empty")
+	}
+	atts, err := readAttachments(files)
+	if err != nil {
+		return err
+	}
+
+	// Answers must go through the primary so the waiting agent is woken up.
+	if !webserver.IsRunning() {
+		return fmt.Errorf("no rishvan-mcp primary is running at %s", webserver.PrimaryURL())
+	}
+	if err := webserver.RemoteRespond(uint(id), text, atts...); err != nil {
+		return err
+	}
+	fmt.Printf("Responded to request #%d\n", id)
+	return nil
+}
+
+// readAttachments loads files for upload, typing each by its extension
+// and content.
+func readAttachments(files []string) ([]db.Attachment, error) {
+	atts := make([]db.Attachment, 0, len(files))
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment: %w", err)
+		}
+		atts = append(atts, db.Attachment{
+			Name:     filepath.Base(path),
+			MIMEType: attachment.DetectType(path, data),
+			Data:     data,
+		})
+	}
+	if err := attachment.Validate(atts); err != nil {
+		return nil, err
+	}
+	return atts, nil
+}
+
+func runAck(args []string) error {
+	fs := newFlagSet("ack", `ack <id>`)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a notification id")
+	}
+	id, err := strconv.ParseUint(positional[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid id %q", positional[0])
+	}
+
+	if !webserver.IsRunning() {
+		return fmt.Errorf("no rishvan-mcp primary is running at %s", webserver.PrimaryURL())
+	}
+	if err := webserver.RemoteAcknowledge(uint(id)); err != nil {
+		return err
+	}
+	fmt.Printf("Acknowledged notification #%d\n", id)
+	return nil
+}
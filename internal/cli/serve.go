@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+	"github.com/tejzpr/rishvan-mcp/internal/handler"
+)
+
+// runServe runs the stdio MCP server for one source.
+func runServe(args []string) error {
+	fs := newFlagSet("serve", "serve --source <source-name> [flags]")
+	fs.StringVar(&config.SourceName, "source", "", "name of the source instance (required)")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if config.SourceName == "" {
+		fs.Usage()
+		return fmt.Errorf("--source <name> is required")
+	}
+
+	s := newMCPServer()
+
+	// Start stdio server
+	if err := server.ServeStdio(s); err != nil {
+		return fmt.Errorf("server error: %w", err)
+	}
+	return nil
+}
+
+// newMCPServer creates the MCP server with all rishvan tools registered.
+func newMCPServer() *server.MCPServer {
+	// Create MCP server
+	s := server.NewMCPServer(
+		"rishvan-mcp",
+		Version,
+		server.WithToolCapabilities(false),
+	)
+
+	// Screenshots, diffs and the like, shown inline in the web UI.
+	attachments := mcp.WithArray("attachments",
+		mcp.Description("Files to show the human with the message, such as screenshots, diffs or charts. Each item is either {name, mime_type, data} with base64 data ({name, mime_type, text} for text files) or an MCP embedded resource {type: \"resource\", resource: {uri, mimeType, blob|text}}. Allowed types: PNG, JPEG, GIF and WebP images, plain text, Markdown, CSV, diffs, logs, JSON and PDF; at most 10 files, 10 MiB each and 25 MiB together."),
+		mcp.Items(map[string]any{"type": "object"}),
+	)
+
+	// Register ask_rishvan tool
+	tool := mcp.NewTool("ask_rishvan",
+		mcp.WithDescription("Ask a human for input, recommendation, or guidance. Opens a web UI for the human to respond."),
+		mcp.WithString("question",
+			mcp.Required(),
+			mcp.Description("The question, recommendation request, or 'what to do next' prompt for the human"),
+		),
+		mcp.WithString("app_name",
+			mcp.Required(),
+			mcp.Description("The name of the application or project context"),
+		),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description("Stop waiting for the human after this many seconds. Waits indefinitely when omitted."),
+		),
+		mcp.WithString("default_response",
+			mcp.Description("Answer to return when timeout_seconds passes without a response. Without it, a timeout is reported as a tool error."),
+		),
+		mcp.WithNumber("thread_id",
+			mcp.Description("Ask a follow-up in an earlier conversation: the thread_id returned by a previous ask_rishvan call. The human sees the earlier questions and answers alongside this one."),
+		),
+		mcp.WithNumber("parent_id",
+			mcp.Description("The request_id of the specific answer this question follows up on. Implies its thread; defaults to the latest request in thread_id."),
+		),
+		attachments,
+	)
+	s.AddTool(tool, handler.AskRishvan)
+
+	// Register ask_rishvan_choice tool
+	choiceTool := mcp.NewTool("ask_rishvan_choice",
+		mcp.WithDescription("Ask a human to pick one of several options. The options are shown as buttons in the web UI and the chosen option is returned as structured content."),
+		mcp.WithString("question",
+			mcp.Required(),
+			mcp.Description("The question the options answer"),
+		),
+		mcp.WithString("app_name",
+			mcp.Required(),
+			mcp.Description("The name of the application or project context"),
+		),
+		mcp.WithArray("options",
+			mcp.Required(),
+			mcp.Description("The options to choose from"),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"label":       map[string]any{"type": "string", "description": "Short label shown on the button and returned when chosen"},
+					"description": map[string]any{"type": "string", "description": "Optional longer explanation of the option"},
+				},
+				"required": []string{"label"},
+			}),
+		),
+		mcp.WithString("default",
+			mcp.Description("Label of the option to highlight as the recommended choice"),
+		),
+		mcp.WithBoolean("allow_other",
+			mcp.Description("Allow the human to answer with free text instead of one of the options"),
+		),
+	)
+	s.AddTool(choiceTool, handler.AskRishvanChoice)
+
+	// Register ask_rishvan_form tool
+	formTool := mcp.NewTool("ask_rishvan_form",
+		mcp.WithDescription("Ask a human to fill in a form generated from a JSON Schema. The submission is validated against the schema and returned as structured content."),
+		mcp.WithString("question",
+			mcp.Required(),
+			mcp.Description("Instructions shown above the form"),
+		),
+		mcp.WithString("app_name",
+			mcp.Required(),
+			mcp.Description("The name of the application or project context"),
+		),
+		mcp.WithObject("schema",
+			mcp.Required(),
+			mcp.Description("JSON Schema of the expected answer. Must be an object schema with properties; supports type, enum, required, minimum/maximum, minLength/maxLength, pattern and items."),
+		),
+	)
+	s.AddTool(formTool, handler.AskRishvanForm)
+
+	// Register notify_rishvan tool
+	notifyTool := mcp.NewTool("notify_rishvan",
+		mcp.WithDescription("Tell the human about progress without waiting for an answer: a finished task, a summary, links to results. Shows up in the web UI feed and returns immediately. Use ask_rishvan instead when you need a decision."),
+		mcp.WithString("message",
+			mcp.Required(),
+			mcp.Description("The update for the human, e.g. what was done and what to look at"),
+		),
+		mcp.WithString("app_name",
+			mcp.Required(),
+			mcp.Description("The name of the application or project context"),
+		),
+		mcp.WithArray("links",
+			mcp.Description("Optional URLs or file paths related to the update"),
+			mcp.WithStringItems(),
+		),
+		attachments,
+	)
+	s.AddTool(notifyTool, handler.NotifyRishvan)
+
+	// Register check_rishvan_messages tool
+	messagesTool := mcp.NewTool("check_rishvan_messages",
+		mcp.WithDescription("Check for messages the human sent while you were working, such as a request to stop or change direction. Returns the unread messages and marks them read. Call it between steps of a long task; unread messages are also appended to the next ask_rishvan result."),
+		mcp.WithString("app_name",
+			mcp.Required(),
+			mcp.Description("The name of the application or project context"),
+		),
+	)
+	s.AddTool(messagesTool, handler.CheckRishvanMessages)
+
+	return s
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/tejzpr/rishvan-mcp/internal/tui"
+	"github.com/tejzpr/rishvan-mcp/internal/webserver"
+)
+
+func runTUI(args []string) error {
+	fs := newFlagSet("tui", "tui [--source S] [--app A] [--no-color]")
+	source := fs.String("source", "", "only show requests from this source")
+	app := fs.String("app", "", "only show requests for this app")
+	noColor := fs.Bool("no-color", os.Getenv("NO_COLOR") != "", "disable colors and screen clearing")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	if !webserver.IsRunning() {
+		return fmt.Errorf("no rishvan-mcp primary is running at %s", webserver.PrimaryURL())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return tui.Run(ctx, os.Stdin, os.Stdout, tui.Options{
+		Source: *source,
+		App:    *app,
+		Color:  !*noColor && isTerminal(os.Stdout),
+	})
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
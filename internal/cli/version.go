@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Version, Commit and Date are set at build time with
+// -ldflags "-X github.com/tejzpr/rishvan-mcp/internal/cli.Version=...".
+// Commit and Date fall back to the VCS stamp Go embeds in the binary.
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+func runVersion(args []string) error {
+	fs := newFlagSet("version", "version")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	commit, date := Commit, Date
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && commit == "":
+				commit = s.Value
+			case s.Key == "vcs.time" && date == "":
+				date = s.Value
+			}
+		}
+	}
+	if commit == "" {
+		commit = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+
+	fmt.Printf("rishvan-mcp %s\n", Version)
+	fmt.Printf("  commit:  %s\n", commit)
+	fmt.Printf("  built:   %s\n", date)
+	fmt.Printf("  go:      %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return nil
+}
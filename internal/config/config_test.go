@@ -0,0 +1,198 @@
+package config
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBaseURL(t *testing.T) {
+	defer func(port int, bind string) { Port, Bind = port, bind }(Port, Bind)
+
+	cases := []struct {
+		bind string
+		want string
+	}{
+		{"", "http://localhost:7000"},
+		{"0.0.0.0", "http://localhost:7000"},
+		{"::", "http://localhost:7000"},
+		{"127.0.0.1", "http://127.0.0.1:7000"},
+		{"::1", "http://[::1]:7000"},
+	}
+	Port = 7000
+	for _, tc := range cases {
+		Bind = tc.bind
+		if got := BaseURL(); got != tc.want {
+			t.Errorf("bind %q: expected %q, got %q", tc.bind, tc.want, got)
+		}
+	}
+}
+
+func TestApplyEnvAndLoad(t *testing.T) {
+	defer func(port int, bind, dataDir, dbPath string) {
+		Port, Bind, DataDir, DBPath = port, bind, dataDir, dbPath
+	}(Port, Bind, DataDir, DBPath)
+
+	dir := t.TempDir()
+	t.Setenv("RISHVAN_PORT", "7001")
+	t.Setenv("RISHVAN_BIND", "127.0.0.1")
+	t.Setenv("RISHVAN_DATA_DIR", dir)
+	t.Setenv("RISHVAN_DB_PATH", "")
+	DBPath = ""
+
+	if err := ApplyEnv(); err != nil {
+		t.Fatalf("ApplyEnv failed: %v", err)
+	}
+	if err := Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if Port != 7001 || Bind != "127.0.0.1" || DataDir != dir {
+		t.Errorf("unexpected settings port=%d bind=%q data-dir=%q", Port, Bind, DataDir)
+	}
+	if DBPath != filepath.Join(dir, "app.db") {
+		t.Errorf("expected db path inside data dir, got %q", DBPath)
+	}
+
+	t.Setenv("RISHVAN_PORT", "not-a-port")
+	if err := ApplyEnv(); err == nil {
+		t.Error("expected error for invalid RISHVAN_PORT")
+	}
+}
+
+func TestOrigins(t *testing.T) {
+	defer func(port int, bind string, allowed []string) {
+		Port, Bind, AllowedOrigins = port, bind, allowed
+	}(Port, Bind, AllowedOrigins)
+
+	Port, Bind = 7002, "10.0.0.5"
+	t.Setenv("RISHVAN_ALLOW_ORIGINS", "http://localhost:5173/, https://rishvan.example")
+	if err := ApplyEnv(); err != nil {
+		t.Fatalf("ApplyEnv failed: %v", err)
+	}
+
+	got := Origins()
+	for _, want := range []string{
+		"http://10.0.0.5:7002",
+		"http://localhost:7002",
+		"http://127.0.0.1:7002",
+		"http://[::1]:7002",
+		"http://localhost:5173",
+		"https://rishvan.example",
+	} {
+		if !slices.Contains(got, want) {
+			t.Errorf("expected %q in %v", want, got)
+		}
+	}
+	if slices.Contains(got, "http://localhost:3000") {
+		t.Errorf("unexpected origin in %v", got)
+	}
+}
+
+func TestTLSSettings(t *testing.T) {
+	defer func(port int, bind, dataDir, cert, key string, selfSigned bool) {
+		Port, Bind, DataDir, TLSCert, TLSKey, TLSSelfSigned = port, bind, dataDir, cert, key, selfSigned
+	}(Port, Bind, DataDir, TLSCert, TLSKey, TLSSelfSigned)
+
+	dir := t.TempDir()
+	Port, Bind, DataDir, TLSCert, TLSKey = 7003, "", dir, "", ""
+	t.Setenv("RISHVAN_TLS_SELF_SIGNED", "true")
+	if err := ApplyEnv(); err != nil {
+		t.Fatalf("ApplyEnv failed: %v", err)
+	}
+	if err := Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if TLSCert != filepath.Join(dir, "tls", "cert.pem") || TLSKey != filepath.Join(dir, "tls", "key.pem") {
+		t.Errorf("unexpected self-signed paths %q %q", TLSCert, TLSKey)
+	}
+	if got := BaseURL(); got != "https://localhost:7003" {
+		t.Errorf("expected an https base URL, got %q", got)
+	}
+	if !slices.Contains(Origins(), "https://127.0.0.1:7003") {
+		t.Errorf("expected https origins, got %v", Origins())
+	}
+
+	TLSSelfSigned, TLSCert, TLSKey = false, "/etc/cert.pem", ""
+	if err := Load(); err == nil {
+		t.Error("expected --tls-cert without --tls-key to fail")
+	}
+}
+
+func TestRetentionSettings(t *testing.T) {
+	defer func(dataDir string, age time.Duration, perSource int, keep []string, archive bool) {
+		DataDir, RetainFor, RetainPerSource, RetainKeep, RetainArchive = dataDir, age, perSource, keep, archive
+	}(DataDir, RetainFor, RetainPerSource, RetainKeep, RetainArchive)
+
+	DataDir = t.TempDir()
+	t.Setenv("RISHVAN_RETAIN_FOR", "90d")
+	t.Setenv("RISHVAN_RETAIN_PER_SOURCE", "500")
+	t.Setenv("RISHVAN_RETAIN_KEEP", "responded, cancelled")
+	t.Setenv("RISHVAN_RETAIN_ARCHIVE", "true")
+	if err := ApplyEnv(); err != nil {
+		t.Fatalf("ApplyEnv failed: %v", err)
+	}
+	if err := Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if RetainFor != 90*24*time.Hour || RetainPerSource != 500 || !RetainArchive || !RetentionEnabled() {
+		t.Errorf("unexpected settings for=%v per-source=%d archive=%v", RetainFor, RetainPerSource, RetainArchive)
+	}
+	if !slices.Equal(RetainKeep, []string{"responded", "cancelled"}) {
+		t.Errorf("unexpected keep list %v", RetainKeep)
+	}
+
+	RetainKeep = []string{"pending"}
+	if err := Load(); err == nil {
+		t.Error("expected pending to be rejected as a --retain-keep status")
+	}
+	t.Setenv("RISHVAN_RETAIN_FOR", "forever")
+	if err := ApplyEnv(); err == nil {
+		t.Error("expected an invalid RISHVAN_RETAIN_FOR to fail")
+	}
+}
+
+func TestDatabaseURL(t *testing.T) {
+	defer func(dataDir, url string) { DataDir, DatabaseURL = dataDir, url }(DataDir, DatabaseURL)
+
+	DataDir = t.TempDir()
+	t.Setenv("RISHVAN_DATABASE_URL", "postgres://rishvan@db.example/rishvan")
+	if err := ApplyEnv(); err != nil {
+		t.Fatalf("ApplyEnv failed: %v", err)
+	}
+	if err := Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if DatabaseURL != "postgres://rishvan@db.example/rishvan" {
+		t.Errorf("unexpected database URL %q", DatabaseURL)
+	}
+
+	DatabaseURL = "mysql://rishvan@db.example/rishvan"
+	if err := Load(); err == nil {
+		t.Error("expected a non-PostgreSQL URL to be rejected")
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	cases := map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+		"90m": 90 * time.Minute,
+	}
+	for in, want := range cases {
+		got, err := ParseAge(in)
+		if err != nil {
+			t.Errorf("ParseAge(%q) failed: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseAge(%q) = %v, want %v", in, got, want)
+		}
+	}
+	for _, in := range []string{"", "d", "-3d", "soon"} {
+		if _, err := ParseAge(in); err == nil {
+			t.Errorf("expected ParseAge(%q) to fail", in)
+		}
+	}
+}
@@ -1,10 +1,17 @@
 package db
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -16,32 +23,176 @@ var (
 	initErr  error
 )
 
+// Init opens and migrates the database: the PostgreSQL one at
+// config.DatabaseURL if set, else the SQLite file at config.DBPath.
 func Init() (*gorm.DB, error) {
 	once.Do(func() {
-		home, err := os.UserHomeDir()
-		if err != nil {
+		if err := config.Load(); err != nil {
 			initErr = err
 			return
 		}
 
-		dir := filepath.Join(home, ".rishvan-mcp")
-		if err := os.MkdirAll(dir, 0755); err != nil {
+		dsn := config.DatabaseURL
+		if dsn == "" {
+			dsn = config.DBPath
+			if err := os.MkdirAll(filepath.Dir(dsn), 0755); err != nil {
+				initErr = err
+				return
+			}
+		}
+
+		instance, initErr = Open(dsn)
+		if initErr != nil {
+			return
+		}
+		initErr = Migrate(instance)
+	})
+	return instance, initErr
+}
+
+// InitReadOnly opens the database like Init, for commands that only read
+// it while the primary may be writing. Nothing is migrated or written, not
+// even the search index, which a build without FTS5 would otherwise detach
+// from under a primary that has it; SQLite files are opened read-only. A
+// database at another schema version than this binary's is refused.
+func InitReadOnly() (*gorm.DB, error) {
+	once.Do(func() {
+		if err := config.Load(); err != nil {
 			initErr = err
 			return
 		}
 
-		dbPath := filepath.Join(dir, "app.db")
-		instance, initErr = gorm.Open(sqlite.Open(dbPath), &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Silent),
-		})
-		if err := instance.AutoMigrate(&Request{}); err != nil {
+		dsn := config.DatabaseURL
+		if dsn == "" {
+			dsn = config.DBPath
+		}
+		instance, initErr = OpenReadOnly(dsn)
+	})
+	return instance, initErr
+}
+
+// InitWithoutMigrating opens the database like Init, for commands that
+// write to it while the primary may be using it. A database at another
+// schema version than this binary's is refused rather than migrated from
+// under the primary. Only a SQLite file that does not exist yet is
+// created and migrated, since nothing else can be using it.
+func InitWithoutMigrating() (*gorm.DB, error) {
+	once.Do(func() {
+		if err := config.Load(); err != nil {
 			initErr = err
 			return
 		}
+
+		dsn := config.DatabaseURL
+		if dsn == "" {
+			dsn = config.DBPath
+		}
+		instance, initErr = openWithoutMigrating(dsn)
 	})
 	return instance, initErr
 }
 
+func openWithoutMigrating(dsn string) (*gorm.DB, error) {
+	fresh := false
+	if !config.IsPostgresURL(dsn) && !strings.HasPrefix(dsn, "file:") {
+		if _, err := os.Stat(dsn); errors.Is(err, os.ErrNotExist) {
+			fresh = true
+			if err := os.MkdirAll(filepath.Dir(dsn), 0755); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	d, err := Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if fresh {
+		err = Migrate(d)
+	} else {
+		err = CheckSchema(d)
+	}
+	if err != nil {
+		if sqlDB, dbErr := d.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+// sqliteOptions let several processes share one SQLite file. WAL lets
+// readers go on while one connection writes, and the busy timeout makes
+// a writer wait for the lock rather than fail. Transactions take the
+// write lock when they begin: one that reads first and then writes could
+// otherwise find another writer got in between, which SQLite resolves by
+// failing it with "database is locked" at once, busy timeout or not.
+const sqliteOptions = "_journal_mode=WAL&_busy_timeout=10000&_txlock=immediate"
+
+// Open connects to the database dsn names: PostgreSQL for a postgres:// or
+// postgresql:// URL, otherwise SQLite, given a file: URI or the path of
+// the file.
+func Open(dsn string) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	if config.IsPostgresURL(dsn) {
+		dialector = postgres.Open(dsn)
+	} else {
+		dialector = sqlite.Open(sqliteURI(dsn, sqliteOptions))
+	}
+	return gorm.Open(dialector, gormConfig())
+}
+
+// sqliteURI turns dsn into a file: URI carrying options. A path is taken
+// as it is, whatever characters it contains; a URI keeps the options it
+// already has.
+func sqliteURI(dsn, options string) string {
+	if strings.HasPrefix(dsn, "file:") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		return dsn + sep + options
+	}
+	path := (&url.URL{Path: filepath.ToSlash(dsn)}).EscapedPath()
+	return (&url.URL{Scheme: "file", Opaque: path, RawQuery: options}).String()
+}
+
+// OpenReadOnly connects to the database dsn names like Open, but opens
+// SQLite files read-only, and checks that the schema is this binary's.
+func OpenReadOnly(dsn string) (*gorm.DB, error) {
+	var d *gorm.DB
+	var err error
+	if config.IsPostgresURL(dsn) {
+		d, err = Open(dsn)
+	} else if _, err = os.Stat(dsn); err != nil && !strings.HasPrefix(dsn, "file:") {
+		return nil, fmt.Errorf("no database at %s: %w", dsn, err)
+	} else {
+		// A read-only connection can neither switch the journal mode
+		// nor take the write lock, so only the busy timeout of
+		// sqliteOptions applies.
+		d, err = gorm.Open(sqlite.Open(sqliteURI(dsn, "mode=ro&_busy_timeout=10000")), gormConfig())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckSchema(d); err != nil {
+		if sqlDB, dbErr := d.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+func gormConfig() *gorm.Config {
+	return &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+		// SQLite keeps times as text and compares them as text, which
+		// only orders instants correctly when they share an offset.
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	}
+}
+
 func Get() *gorm.DB {
 	return instance
 }
@@ -1,6 +1,9 @@
 package db
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -19,6 +22,9 @@ func TestCreateAndFetchRequest(t *testing.T) {
 	if req.ID == 0 {
 		t.Fatal("expected non-zero ID after create")
 	}
+	if len(req.UUID) != 36 {
+		t.Errorf("expected a UUID to be assigned on create, got %q", req.UUID)
+	}
 
 	var fetched Request
 	if err := instance.First(&fetched, req.ID).Error; err != nil {
@@ -126,3 +132,85 @@ func TestFilterByAppName(t *testing.T) {
 		t.Errorf("expected 2 alpha requests, got %d", len(requests))
 	}
 }
+
+func TestOpenReadOnly(t *testing.T) {
+	// URI syntax in the path is taken literally.
+	dir := filepath.Join(t.TempDir(), "data?x=1#50% off")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "app.db")
+	if _, err := OpenReadOnly(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected a missing database to be reported, got %v", err)
+	}
+
+	d, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := Migrate(d); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	d.Create(&Request{SourceName: "ide", AppName: "app", Question: "q", Status: "pending"})
+
+	ro, err := OpenReadOnly(path)
+	if err != nil {
+		t.Fatalf("OpenReadOnly failed: %v", err)
+	}
+	var count int64
+	if err := ro.Model(&Request{}).Count(&count).Error; err != nil || count != 1 {
+		t.Errorf("expected to read one request, got %d (%v)", count, err)
+	}
+	if err := ro.Create(&Request{SourceName: "ide", AppName: "app", Question: "q2"}).Error; err == nil {
+		t.Error("expected writes through a read-only connection to fail")
+	}
+	if sqlDB, err := ro.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	// A URI keeps its own options.
+	ro, err = OpenReadOnly(sqliteURI(path, "cache=private"))
+	if err != nil {
+		t.Fatalf("OpenReadOnly of a URI failed: %v", err)
+	}
+	if err := ro.Create(&Request{SourceName: "ide", AppName: "app", Question: "q2"}).Error; err == nil {
+		t.Error("expected writes through a read-only URI to fail")
+	}
+	if sqlDB, err := ro.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	// A database this binary would have to migrate is left alone.
+	d.Exec("DELETE FROM schema_migrations WHERE version = ?", SchemaVersion())
+	if _, err := OpenReadOnly(path); !errors.Is(err, ErrSchemaTooOld) {
+		t.Errorf("expected ErrSchemaTooOld, got %v", err)
+	}
+	if sqlDB, err := d.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+func TestOpenWithoutMigrating(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "app.db")
+
+	// A database nobody has created yet is created at this version.
+	d, err := openWithoutMigrating(path)
+	if err != nil {
+		t.Fatalf("openWithoutMigrating of a new database failed: %v", err)
+	}
+	if err := CheckSchema(d); err != nil {
+		t.Errorf("expected the new database to be migrated, got %v", err)
+	}
+
+	// An existing one that needs migrating is left to the primary.
+	d.Exec("DELETE FROM schema_migrations WHERE version = ?", SchemaVersion())
+	if sqlDB, err := d.DB(); err == nil {
+		sqlDB.Close()
+	}
+	if _, err := openWithoutMigrating(path); !errors.Is(err, ErrSchemaTooOld) {
+		t.Fatalf("expected ErrSchemaTooOld, got %v", err)
+	}
+	if _, err := OpenReadOnly(path); !errors.Is(err, ErrSchemaTooOld) {
+		t.Errorf("expected the database to stay unmigrated, got %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The schema is built by numbered migrations, applied in order and
+// recorded in schema_migrations so that each runs once per database.
+// Changing a model means appending a migration; existing ones must never
+// change, since databases out there have already run them. Steps work on
+// the tables as they stood at that version, through structs of their own
+// or plain SQL, never through the models, which keep changing.
+var migrations = []migration{
+	{1, "create tables", func(tx *gorm.DB) error {
+		// Databases from before migrations were made by AutoMigrate
+		// alone; running it once more brings them to the baseline too.
+		return tx.AutoMigrate(&baselineRequest{}, &baselineMessage{}, &baselineAttachment{})
+	}},
+	{2, "start a thread for every request stored before threads", func(tx *gorm.DB) error {
+		return tx.Table("requests").Where("thread_id IS NULL OR thread_id = 0").
+			UpdateColumn("thread_id", gorm.Expr("id")).Error
+	}},
+	{3, "give every request stored before export a UUID", func(tx *gorm.DB) error {
+		expr := randomUUID
+		if tx.Dialector.Name() == "postgres" {
+			expr = "gen_random_uuid()::text"
+		}
+		return tx.Table("requests").Where("uuid IS NULL OR uuid = ''").
+			UpdateColumn("uuid", gorm.Expr(expr)).Error
+	}},
+	{4, "store SQLite timestamps in UTC", func(tx *gorm.DB) error {
+		// PostgreSQL compares timestamps as instants already.
+		if tx.Dialector.Name() != "sqlite" {
+			return nil
+		}
+		return timestampsToUTC(tx)
+	}},
+	{5, "number the attachments of a request uniquely", func(tx *gorm.DB) error {
+		if err := renumberAttachments(tx); err != nil {
+			return err
+		}
+		return tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_attachments_request_idx ON attachments (request_id, idx)").Error
+	}},
+	{6, "record the host of each request's owner", func(tx *gorm.DB) error {
+		return addColumn(tx, &ownerHostRequest{}, "OwnerHost")
+	}},
+	{7, "keep attachment contents in the database", func(tx *gorm.DB) error {
+		return addColumn(tx, &contentAttachment{}, "Content")
+	}},
+}
+
+// baselineRequest, baselineMessage and baselineAttachment are the models
+// as migration 1 creates them.
+type baselineRequest struct {
+	gorm.Model
+	UUID            string `gorm:"column:uuid;uniqueIndex"`
+	SourceName      string `gorm:"column:source_name;index;not null;default:''"`
+	AppName         string `gorm:"index;not null"`
+	Kind            string `gorm:"default:question;not null"`
+	Question        string `gorm:"type:text;not null"`
+	Options         string `gorm:"type:text"`
+	DefaultOption   string
+	AllowOther      bool
+	Schema          string `gorm:"type:text"`
+	ExpiresAt       *time.Time
+	DefaultResponse string `gorm:"type:text"`
+	Response        string `gorm:"type:text"`
+	Status          string `gorm:"default:pending;not null;index"`
+	OwnerPID        int    `gorm:"column:owner_pid"`
+	RespondedAt     *time.Time
+	ThreadID        uint `gorm:"index"`
+	ParentID        *uint
+	Attachments     []baselineAttachment `gorm:"foreignKey:RequestID"`
+}
+
+func (baselineRequest) TableName() string { return "requests" }
+
+type baselineMessage struct {
+	gorm.Model
+	SourceName string     `gorm:"index;not null"`
+	AppName    string     `gorm:"index;not null;default:''"`
+	Body       string     `gorm:"type:text;not null"`
+	ReadAt     *time.Time `gorm:"index"`
+}
+
+func (baselineMessage) TableName() string { return "messages" }
+
+type baselineAttachment struct {
+	gorm.Model
+	RequestID uint `gorm:"index;not null"`
+	Index     int  `gorm:"column:idx;not null"`
+	Name      string
+	MIMEType  string `gorm:"not null"`
+	Size      int64
+	FromHuman bool   `gorm:"not null;default:false"`
+	Path      string `gorm:"not null"`
+}
+
+func (baselineAttachment) TableName() string { return "attachments" }
+
+// ownerHostRequest is the column migration 6 adds to requests.
+type ownerHostRequest struct {
+	OwnerHost string `gorm:"column:owner_host;not null;default:''"`
+}
+
+func (ownerHostRequest) TableName() string { return "requests" }
+
+// contentAttachment is the column migration 7 adds to attachments.
+type contentAttachment struct {
+	Content []byte `gorm:"column:content"`
+}
+
+func (contentAttachment) TableName() string { return "attachments" }
+
+// addColumn adds field of model to its table unless it is there already.
+func addColumn(tx *gorm.DB, model any, field string) error {
+	if tx.Migrator().HasColumn(model, field) {
+		return nil
+	}
+	return tx.Migrator().AddColumn(model, field)
+}
+
+// timestampColumns are the time columns of every table as of migration 4.
+var timestampColumns = map[string][]string{
+	"requests":    {"created_at", "updated_at", "deleted_at", "expires_at", "responded_at"},
+	"messages":    {"created_at", "updated_at", "deleted_at", "read_at"},
+	"attachments": {"created_at", "updated_at", "deleted_at"},
+}
+
+// timestampsToUTC rewrites the times SQLite stored with the offset of the
+// zone they were written in. SQLite has no function to convert them
+// without dropping the nanoseconds, so each goes through Go.
+func timestampsToUTC(tx *gorm.DB) error {
+	for table, columns := range timestampColumns {
+		for _, column := range columns {
+			var rows []struct {
+				ID uint
+				At time.Time
+			}
+			err := tx.Table(table).Select("id, "+column+" AS at").
+				Where(column+" IS NOT NULL AND "+column+" NOT LIKE ?", "%+00:00").Scan(&rows).Error
+			if err != nil {
+				return fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+			}
+			for _, row := range rows {
+				if err := tx.Table(table).Where("id = ?", row.ID).UpdateColumn(column, row.At.UTC()).Error; err != nil {
+					return fmt.Errorf("failed to update %s.%s: %w", table, column, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// renumberAttachments numbers the attachments of every request that two
+// racing answers left with the same number again, in the order they were
+// stored.
+func renumberAttachments(tx *gorm.DB) error {
+	var requestIDs []uint
+	if err := tx.Table("attachments").Distinct("request_id").
+		Where("request_id IN (?)", tx.Table("attachments").Select("request_id").Group("request_id, idx").Having("count(*) > 1")).
+		Pluck("request_id", &requestIDs).Error; err != nil {
+		return fmt.Errorf("failed to find duplicate attachment numbers: %w", err)
+	}
+	for _, requestID := range requestIDs {
+		var ids []uint
+		if err := tx.Table("attachments").Where("request_id = ?", requestID).Order("idx, id").Pluck("id", &ids).Error; err != nil {
+			return fmt.Errorf("failed to renumber attachments of request %d: %w", requestID, err)
+		}
+		for i, id := range ids {
+			if err := tx.Table("attachments").Where("id = ?", id).UpdateColumn("idx", i).Error; err != nil {
+				return fmt.Errorf("failed to renumber attachments of request %d: %w", requestID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// randomUUID is an SQLite expression for a random version 4 UUID.
+const randomUUID = `lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' ||
+	substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) ||
+	substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))`
+
+type migration struct {
+	version int
+	name    string
+	up      func(tx *gorm.DB) error
+}
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer
+// binary than this one. Running anyway could corrupt data this binary
+// does not know about.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+// ErrSchemaTooOld is returned by CheckSchema when the database still needs
+// migrations that only a read-write open applies.
+var ErrSchemaTooOld = errors.New("database schema is older than this binary")
+
+// CheckSchema verifies, without changing anything, that d is at exactly
+// the schema version of this binary.
+func CheckSchema(d *gorm.DB) error {
+	current := 0
+	if d.Migrator().HasTable("schema_migrations") {
+		var err error
+		if current, err = appliedVersion(d); err != nil {
+			return err
+		}
+	}
+	switch {
+	case current > SchemaVersion():
+		return fmt.Errorf("%w: it is at version %d, this rishvan-mcp only knows up to %d; upgrade rishvan-mcp",
+			ErrSchemaTooNew, current, SchemaVersion())
+	case current < SchemaVersion():
+		return fmt.Errorf("%w: it is at version %d, this rishvan-mcp needs %d; start rishvan-mcp serve once to upgrade it",
+			ErrSchemaTooOld, current, SchemaVersion())
+	}
+	return nil
+}
+
+// SchemaVersion is the version of the newest migration this binary has.
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// Migrate brings d up to SchemaVersion, then sets up the search index as
+// far as this build supports it. A database already migrated further is
+// refused with ErrSchemaTooNew.
+func Migrate(d *gorm.DB) error {
+	if err := d.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`).Error; err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	current, err := appliedVersion(d)
+	if err != nil {
+		return err
+	}
+	if current > SchemaVersion() {
+		return fmt.Errorf("%w: it is at version %d, this rishvan-mcp only knows up to %d; upgrade rishvan-mcp",
+			ErrSchemaTooNew, current, SchemaVersion())
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		err := d.Transaction(func(tx *gorm.DB) error {
+			if err := m.up(tx); err != nil {
+				return err
+			}
+			return tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				m.version, m.name, time.Now().UTC()).Error
+		})
+		if err != nil {
+			// Another process sharing the database may have applied it
+			// first.
+			if v, verr := appliedVersion(d); verr == nil && v >= m.version {
+				continue
+			}
+			return fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+
+	return InitSearch(d)
+}
+
+func appliedVersion(d *gorm.DB) (int, error) {
+	var version int
+	if err := d.Raw("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version).Error; err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
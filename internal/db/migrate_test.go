@@ -0,0 +1,143 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	setupTestDB(t)
+
+	var versions []int
+	instance.Raw("SELECT version FROM schema_migrations ORDER BY version").Scan(&versions)
+	if len(versions) != len(migrations) || versions[len(versions)-1] != SchemaVersion() {
+		t.Fatalf("expected every migration to be recorded, got %v", versions)
+	}
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("migration %q has version %d, want %d: versions must be consecutive", m.name, m.version, i+1)
+		}
+	}
+
+	// Running again is a no-op.
+	if err := Migrate(instance); err != nil {
+		t.Fatalf("second Migrate failed: %v", err)
+	}
+	var count int64
+	instance.Raw("SELECT count(*) FROM schema_migrations").Scan(&count)
+	if count != int64(len(migrations)) {
+		t.Errorf("expected %d recorded migrations after a second run, got %d", len(migrations), count)
+	}
+}
+
+// legacyRequest is the Request model of the first release.
+type legacyRequest struct {
+	gorm.Model
+	SourceName  string `gorm:"column:source_name;index;not null;default:''"`
+	AppName     string `gorm:"index;not null"`
+	Question    string `gorm:"type:text;not null"`
+	Response    string `gorm:"type:text"`
+	Status      string `gorm:"default:pending;not null;index"`
+	RespondedAt *time.Time
+}
+
+func (legacyRequest) TableName() string { return "requests" }
+
+func TestMigrateDatabaseFromBeforeMigrations(t *testing.T) {
+	instance = openTestDB(t)
+
+	// The requests table as the first release's AutoMigrate left it.
+	if err := instance.AutoMigrate(&legacyRequest{}); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+	legacy := legacyRequest{SourceName: "ide", AppName: "app", Question: "old question", Response: "old answer", Status: "responded"}
+	if err := instance.Create(&legacy).Error; err != nil {
+		t.Fatalf("failed to store legacy request: %v", err)
+	}
+
+	if err := Migrate(instance); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	var req Request
+	if err := instance.First(&req).Error; err != nil {
+		t.Fatalf("failed to read the legacy request: %v", err)
+	}
+	if req.Question != "old question" || req.Response != "old answer" {
+		t.Errorf("legacy request changed: %+v", req)
+	}
+	if req.ThreadID != req.ID {
+		t.Errorf("expected the legacy request to start its own thread, got thread %d", req.ThreadID)
+	}
+	if len(req.UUID) != 36 || req.UUID[14] != '4' {
+		t.Errorf("expected a version 4 UUID, got %q", req.UUID)
+	}
+	if req.Kind != "question" {
+		t.Errorf("expected the kind column to default to question, got %q", req.Kind)
+	}
+	for _, table := range []any{&Message{}, &Attachment{}} {
+		if !instance.Migrator().HasTable(table) {
+			t.Errorf("expected table for %T to be created", table)
+		}
+	}
+
+	// The migrated database takes new requests.
+	if err := instance.Create(&Request{SourceName: "ide", AppName: "app", Question: "new", Status: "pending"}).Error; err != nil {
+		t.Fatalf("failed to create a request after migrating: %v", err)
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	setupTestDB(t)
+	instance.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'from the future', CURRENT_TIMESTAMP)", SchemaVersion()+1)
+
+	err := Migrate(instance)
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestMigrateStoresTimestampsInUTC(t *testing.T) {
+	setupTestDB(t)
+	// A request written by a binary from before migration 4, in a zone
+	// west of UTC.
+	instance.Exec("INSERT INTO requests (created_at, updated_at, source_name, app_name, question, status, thread_id, uuid) VALUES (?, ?, 'ide', 'app', 'q', 'responded', 1, 'u')",
+		"2025-01-31 23:00:00.123456789-05:00", "2025-01-31 23:00:00.123456789-05:00")
+	instance.Exec("DELETE FROM schema_migrations WHERE version >= 4")
+
+	if err := Migrate(instance); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	var stored string
+	instance.Raw("SELECT CAST(created_at AS TEXT) FROM requests").Scan(&stored)
+	if stored != "2025-02-01 04:00:00.123456789+00:00" {
+		t.Errorf("expected the creation time in UTC, got %q", stored)
+	}
+}
+
+func TestMigrateRenumbersDuplicateAttachments(t *testing.T) {
+	setupTestDB(t)
+	// Two answers that raced before attachment numbers were unique.
+	instance.Exec("DROP INDEX idx_attachments_request_idx")
+	for _, name := range []string{"a.log", "b.log", "c.log"} {
+		instance.Create(&Attachment{RequestID: 7, Index: 0, Name: name, MIMEType: "text/plain", Path: "7/" + name})
+	}
+	instance.Exec("DELETE FROM schema_migrations WHERE version >= 5")
+
+	if err := Migrate(instance); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	var atts []Attachment
+	instance.Order("id").Find(&atts)
+	for i, a := range atts {
+		if a.Index != i {
+			t.Errorf("expected %s to be numbered %d, got %d", a.Name, i, a.Index)
+		}
+	}
+	if err := instance.Create(&Attachment{RequestID: 7, Index: 0, Name: "d.log", MIMEType: "text/plain", Path: "7/d.log"}).Error; err == nil {
+		t.Error("expected a duplicate number to be refused")
+	}
+}
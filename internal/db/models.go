@@ -1,17 +1,84 @@
 package db
 
 import (
+	"encoding/json"
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// Option is one selectable answer of a "choice" request.
+type Option struct {
+	Label       string `json:"label"`
+	Description string `json:"description,omitempty"`
+}
+
+// Request is a question asked by an agent. ExpiresAt is when a pending
+// request times out (nil waits forever) and OwnerPID is the process whose
+// agent is waiting for the response, on host OwnerHost (see
+// process.HostID; empty for requests stored before hosts were recorded).
+// Follow-up questions share the ThreadID of the request that started the
+// conversation, which is its own ID, and point at the request they follow
+// up on through ParentID. UUID identifies the request across databases,
+// for export and import.
 type Request struct {
 	gorm.Model
-	SourceName  string     `json:"source_name" gorm:"column:source_name;index;not null;default:''"`
-	AppName     string     `json:"app_name" gorm:"index;not null"`
-	Question    string     `json:"question" gorm:"type:text;not null"`
-	Response    string     `json:"response" gorm:"type:text"`
-	Status      string     `json:"status" gorm:"default:pending;not null;index"`
-	RespondedAt *time.Time `json:"responded_at"`
+	UUID            string          `json:"uuid" gorm:"column:uuid;uniqueIndex"`
+	SourceName      string          `json:"source_name" gorm:"column:source_name;index;not null;default:''"`
+	AppName         string          `json:"app_name" gorm:"index;not null"`
+	Kind            string          `json:"kind" gorm:"default:question;not null"`
+	Question        string          `json:"question" gorm:"type:text;not null"`
+	Options         []Option        `json:"options,omitempty" gorm:"serializer:json;type:text"`
+	DefaultOption   string          `json:"default_option,omitempty"`
+	AllowOther      bool            `json:"allow_other"`
+	Schema          json.RawMessage `json:"schema,omitempty" gorm:"serializer:json;type:text"`
+	ExpiresAt       *time.Time      `json:"expires_at"`
+	DefaultResponse string          `json:"default_response,omitempty" gorm:"type:text"`
+	Response        string          `json:"response" gorm:"type:text"`
+	Status          string          `json:"status" gorm:"default:pending;not null;index"`
+	OwnerPID        int             `json:"owner_pid" gorm:"column:owner_pid"`
+	OwnerHost       string          `json:"owner_host,omitempty" gorm:"column:owner_host;not null;default:''"`
+	RespondedAt     *time.Time      `json:"responded_at"`
+	ThreadID        uint            `json:"thread_id" gorm:"index"`
+	ParentID        *uint           `json:"parent_id,omitempty"`
+	Attachments     []Attachment    `json:"attachments,omitempty" gorm:"foreignKey:RequestID"`
+}
+
+// BeforeCreate gives every new request its UUID.
+func (r *Request) BeforeCreate(tx *gorm.DB) error {
+	if r.UUID == "" {
+		r.UUID = uuid.NewString()
+	}
+	return nil
+}
+
+// Attachment is a file sent along with a request, such as a screenshot or
+// a diff, or with the human's answer to it (FromHuman). Its content lives
+// on disk at Path, relative to config.AttachmentsDir, or, when Path is
+// empty, in the row's content column, which the model leaves out so that
+// loading attachments does not load their bytes. Data only carries it
+// while it is being stored. Index numbers the attachments of a request
+// from 0, the agent's first.
+type Attachment struct {
+	gorm.Model
+	RequestID uint   `json:"request_id" gorm:"index;not null"`
+	Index     int    `json:"index" gorm:"column:idx;not null"`
+	Name      string `json:"name"`
+	MIMEType  string `json:"mime_type" gorm:"not null"`
+	Size      int64  `json:"size"`
+	FromHuman bool   `json:"from_human" gorm:"not null;default:false"`
+	Path      string `json:"-" gorm:"not null"`
+	Data      []byte `json:"data,omitempty" gorm:"-"`
+}
+
+// Message is a note from the human to the agents of a source, sent while
+// they work rather than in answer to a request. An empty AppName addresses
+// every app of the source. ReadAt is set once an agent has received it.
+type Message struct {
+	gorm.Model
+	SourceName string     `json:"source_name" gorm:"index;not null"`
+	AppName    string     `json:"app_name" gorm:"index;not null;default:''"`
+	Body       string     `json:"body" gorm:"type:text;not null"`
+	ReadAt     *time.Time `json:"read_at" gorm:"index"`
 }
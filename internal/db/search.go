@@ -0,0 +1,309 @@
+package db
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// The full-text index is an FTS5 table over the question and response of
+// every request, kept in sync by triggers. FTS5 is only compiled into
+// SQLite with the sqlite_fts5 build tag; without it Search falls back to
+// LIKE matching.
+//
+// Binaries with and without FTS5 may share a database, so the index is not
+// a numbered migration: every start brings it in line with the build. The
+// triggers are what tie the index to the requests table; a build without
+// FTS5 drops them, since every write would fail on them, and the next
+// build with FTS5 puts them back and rebuilds the index.
+//
+// PostgreSQL databases have no index and always use LIKE matching.
+const ftsTable = "requests_fts"
+
+var ftsSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS requests_fts USING fts5(question, response, content='requests', content_rowid='id', tokenize='porter unicode61')`,
+	`CREATE TRIGGER IF NOT EXISTS requests_fts_insert AFTER INSERT ON requests BEGIN
+		INSERT INTO requests_fts(rowid, question, response) VALUES (new.id, new.question, new.response);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS requests_fts_delete AFTER DELETE ON requests BEGIN
+		INSERT INTO requests_fts(requests_fts, rowid, question, response) VALUES ('delete', old.id, old.question, old.response);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS requests_fts_update AFTER UPDATE OF question, response ON requests BEGIN
+		INSERT INTO requests_fts(requests_fts, rowid, question, response) VALUES ('delete', old.id, old.question, old.response);
+		INSERT INTO requests_fts(rowid, question, response) VALUES (new.id, new.question, new.response);
+	END`,
+	// Index the requests stored while the index was missing or detached.
+	`INSERT INTO requests_fts(requests_fts) VALUES ('rebuild')`,
+}
+
+var ftsTriggers = []string{"requests_fts_insert", "requests_fts_delete", "requests_fts_update"}
+
+// InitSearch creates or reattaches the full-text index if this SQLite
+// build supports it, and detaches it otherwise.
+func InitSearch(d *gorm.DB) error {
+	if d.Dialector.Name() != "sqlite" {
+		return nil
+	}
+	if !ftsAvailable(d) {
+		for _, name := range ftsTriggers {
+			if err := d.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+				return fmt.Errorf("failed to detach search index: %w", err)
+			}
+		}
+		return nil
+	}
+	if hasFTS(d) {
+		return nil
+	}
+
+	return d.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range ftsSchema {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("failed to create search index: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ftsAvailable reports whether this SQLite build has FTS5.
+func ftsAvailable(d *gorm.DB) bool {
+	if d.Exec("CREATE VIRTUAL TABLE temp.fts_probe USING fts5(x)").Error != nil {
+		return false
+	}
+	d.Exec("DROP TABLE temp.fts_probe")
+	return true
+}
+
+// hasFTS reports whether the index is in place and kept up to date.
+func hasFTS(d *gorm.DB) bool {
+	if d.Dialector.Name() != "sqlite" {
+		return false
+	}
+	var n int64
+	d.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'trigger' AND name = ?", ftsTriggers[0]).Scan(&n)
+	return n > 0
+}
+
+// SearchOptions narrows a search to one source or app.
+type SearchOptions struct {
+	SourceName string
+	AppName    string
+	Limit      int
+}
+
+// SearchResult is a request matching a search, with an HTML-escaped
+// excerpt of its question or response in which the matched terms are
+// wrapped in <mark>.
+type SearchResult struct {
+	Request
+	Snippet string `json:"snippet"`
+}
+
+// Snippet highlight markers, swapped for <mark> after escaping.
+const (
+	markOpen  = "\x02"
+	markClose = "\x03"
+)
+
+// Search finds the requests whose question or response contains every
+// word of q, the last one as a prefix. With the full-text index results
+// are ranked by relevance, otherwise newest first.
+func Search(d *gorm.DB, q string, opts SearchOptions) ([]SearchResult, error) {
+	terms := searchTerms(q)
+	if len(terms) == 0 {
+		return []SearchResult{}, nil
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 50
+	}
+	if hasFTS(d) {
+		return searchFTS(d, terms, opts)
+	}
+	return searchLike(d, terms, opts)
+}
+
+func searchTerms(q string) []string {
+	var terms []string
+	for _, t := range strings.Fields(q) {
+		if t = strings.Trim(t, `"*`); t != "" {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+func searchFTS(d *gorm.DB, terms []string, opts SearchOptions) ([]SearchResult, error) {
+	// Quote every term so that user input is never read as FTS5 syntax.
+	match := make([]string, len(terms))
+	for i, t := range terms {
+		match[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+	}
+	match[len(match)-1] += "*"
+
+	var rows []struct {
+		ID      uint
+		Snippet string
+	}
+	query := d.Table(ftsTable).
+		Select("requests.id AS id, snippet(requests_fts, -1, ?, ?, '…', 16) AS snippet", markOpen, markClose).
+		Joins("JOIN requests ON requests.id = requests_fts.rowid").
+		Where("requests_fts MATCH ? AND requests.deleted_at IS NULL", strings.Join(match, " ")).
+		Order("bm25(requests_fts)").
+		Limit(opts.Limit)
+	query = filterSearch(query, opts)
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to search requests: %w", err)
+	}
+
+	ids := make([]uint, len(rows))
+	for i, r := range rows {
+		ids[i] = r.ID
+	}
+	byID, err := loadRequests(d, ids)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, 0, len(rows))
+	for _, r := range rows {
+		if req, ok := byID[r.ID]; ok {
+			results = append(results, SearchResult{Request: req, Snippet: highlight(r.Snippet)})
+		}
+	}
+	return results, nil
+}
+
+func searchLike(d *gorm.DB, terms []string, opts SearchOptions) ([]SearchResult, error) {
+	// SQLite's LIKE ignores case, PostgreSQL's needs ILIKE.
+	like := "LIKE"
+	if d.Dialector.Name() == "postgres" {
+		like = "ILIKE"
+	}
+	query := d.Model(&Request{}).Preload("Attachments").Order("created_at DESC").Limit(opts.Limit)
+	for _, t := range terms {
+		pattern := "%" + escapeLike(t) + "%"
+		query = query.Where(fmt.Sprintf(`(question %[1]s ? ESCAPE '\' OR response %[1]s ? ESCAPE '\')`, like), pattern, pattern)
+	}
+	query = filterSearch(query, opts)
+	var requests []Request
+	if err := query.Find(&requests).Error; err != nil {
+		return nil, fmt.Errorf("failed to search requests: %w", err)
+	}
+
+	results := make([]SearchResult, len(requests))
+	for i, r := range requests {
+		text := r.Question
+		if !containsAny(text, terms) {
+			text = r.Response
+		}
+		results[i] = SearchResult{Request: r, Snippet: highlight(likeSnippet(text, terms))}
+	}
+	return results, nil
+}
+
+func filterSearch(query *gorm.DB, opts SearchOptions) *gorm.DB {
+	if opts.SourceName != "" {
+		query = query.Where("requests.source_name = ?", opts.SourceName)
+	}
+	if opts.AppName != "" {
+		query = query.Where("requests.app_name = ?", opts.AppName)
+	}
+	return query
+}
+
+func loadRequests(d *gorm.DB, ids []uint) (map[uint]Request, error) {
+	byID := make(map[uint]Request, len(ids))
+	if len(ids) == 0 {
+		return byID, nil
+	}
+	var requests []Request
+	if err := d.Preload("Attachments").Where("id IN ?", ids).Find(&requests).Error; err != nil {
+		return nil, fmt.Errorf("failed to load requests: %w", err)
+	}
+	for _, r := range requests {
+		byID[r.ID] = r
+	}
+	return byID, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func containsAny(text string, terms []string) bool {
+	for _, t := range terms {
+		if indexFold(text, t) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// likeSnippet cuts an excerpt of about 120 characters around the first
+// match in text and marks every match, like FTS5's snippet function.
+func likeSnippet(text string, terms []string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	start := -1
+	for _, t := range terms {
+		if i := indexFold(text, t); i >= 0 && (start < 0 || i < start) {
+			start = i
+		}
+	}
+	from, to := 0, len(text)
+	if start > 40 {
+		from = start - 40
+	}
+	if to-from > 120 {
+		to = from + 120
+	}
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to++
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	for i := from; i < to; {
+		matched := 0
+		for _, t := range terms {
+			if n := len(t); n > matched && i+n <= to && strings.EqualFold(text[i:i+n], t) {
+				matched = n
+			}
+		}
+		if matched > 0 {
+			b.WriteString(markOpen + text[i:i+matched] + markClose)
+			i += matched
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		b.WriteString(text[i : i+size])
+		i += size
+	}
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// indexFold is strings.Index ignoring case.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// highlight escapes a snippet for HTML and turns its markers into <mark>.
+func highlight(snippet string) string {
+	s := html.EscapeString(snippet)
+	return strings.NewReplacer(markOpen, "<mark>", markClose, "</mark>").Replace(s)
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func seedSearch(t *testing.T) []Request {
+	t.Helper()
+	reqs := []Request{
+		{SourceName: "ide-a", AppName: "api", Question: "Should the migration drop the legacy column?", Status: "pending"},
+		{SourceName: "ide-a", AppName: "web", Question: "Which color for the <button>?", Status: "pending"},
+		{SourceName: "ide-b", AppName: "api", Question: "Ship it?", Status: "pending"},
+	}
+	for i := range reqs {
+		if err := instance.Create(&reqs[i]).Error; err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+	}
+	return reqs
+}
+
+func TestSearchQuestionsAndResponses(t *testing.T) {
+	setupTestDB(t)
+	reqs := seedSearch(t)
+
+	// Responses are searchable once given.
+	instance.Model(&Request{}).Where("id = ?", reqs[2].ID).Updates(map[string]interface{}{
+		"response": "Yes, after the migration finishes", "status": "responded",
+	})
+
+	results, err := Search(instance, "migration", SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	for _, r := range results {
+		if !strings.Contains(r.Snippet, "<mark>migration</mark>") {
+			t.Errorf("expected a highlighted snippet, got %q", r.Snippet)
+		}
+	}
+
+	results, _ = Search(instance, "migration", SearchOptions{SourceName: "ide-b"})
+	if len(results) != 1 || results[0].ID != reqs[2].ID {
+		t.Errorf("expected only request %d for ide-b, got %+v", reqs[2].ID, results)
+	}
+
+	// Every word must match, the last one as a prefix.
+	results, _ = Search(instance, "legacy col", SearchOptions{})
+	if len(results) != 1 || results[0].ID != reqs[0].ID {
+		t.Errorf("expected only request %d, got %+v", reqs[0].ID, results)
+	}
+}
+
+func TestSearchEscapesSnippetsAndQueries(t *testing.T) {
+	setupTestDB(t)
+	seedSearch(t)
+
+	results, err := Search(instance, "color", SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || !strings.Contains(results[0].Snippet, "&lt;button&gt;") {
+		t.Fatalf("expected an HTML-escaped snippet, got %+v", results)
+	}
+
+	// Query syntax is matched literally, never parsed.
+	for _, q := range []string{`"`, `drop OR`, `col*umn NEAR(`, `100%`} {
+		if _, err := Search(instance, q, SearchOptions{}); err != nil {
+			t.Errorf("Search(%q) failed: %v", q, err)
+		}
+	}
+	if results, _ := Search(instance, "   ", SearchOptions{}); len(results) != 0 {
+		t.Errorf("expected no results for a blank query, got %d", len(results))
+	}
+}
+
+func TestSearchSkipsDeletedRequests(t *testing.T) {
+	setupTestDB(t)
+	reqs := seedSearch(t)
+
+	instance.Delete(&Request{}, reqs[0].ID)
+	instance.Unscoped().Delete(&Request{}, reqs[2].ID)
+	if results, _ := Search(instance, "migration", SearchOptions{}); len(results) != 0 {
+		t.Errorf("expected deleted requests to be skipped, got %+v", results)
+	}
+}
+
+func TestSearchIndexReattaches(t *testing.T) {
+	setupTestDB(t)
+
+	// What a build without FTS5 leaves behind: no triggers, and requests
+	// the index has not seen.
+	for _, name := range ftsTriggers {
+		instance.Exec("DROP TRIGGER IF EXISTS " + name)
+	}
+	if err := instance.Create(&Request{SourceName: "ide", AppName: "app", Question: "stored while detached", Status: "responded"}).Error; err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	if err := InitSearch(instance); err != nil {
+		t.Fatalf("InitSearch failed: %v", err)
+	}
+	results, err := Search(instance, "detached", SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("expected the request stored while detached to be found, got %d results", len(results))
+	}
+	if ftsAvailable(instance) != hasFTS(instance) {
+		t.Errorf("expected the index to be attached exactly when FTS5 is available")
+	}
+}
@@ -8,17 +8,31 @@ import (
 	"gorm.io/gorm/logger"
 )
 
-// setupTestDB creates an in-memory SQLite DB for testing and sets the package-level instance.
-func setupTestDB(t *testing.T) {
+// openTestDB opens an empty in-memory SQLite DB. It is limited to one
+// connection, since every connection to ":memory:" gets a database of its
+// own.
+func openTestDB(t *testing.T) *gorm.DB {
 	t.Helper()
-	var err error
-	instance, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+	d, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
 		t.Fatalf("failed to open test db: %v", err)
 	}
-	if err := instance.AutoMigrate(&Request{}); err != nil {
+	sqlDB, err := d.DB()
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return d
+}
+
+// setupTestDB creates a migrated in-memory SQLite DB for testing and sets the package-level instance.
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	instance = openTestDB(t)
+	if err := Migrate(instance); err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
 }
@@ -0,0 +1,233 @@
+// Package export writes request history as JSON Lines, CSV or a Markdown
+// decision log, and imports the first two back. Requests are identified
+// by their UUID, so importing the same export twice adds nothing, and
+// threads are linked by the UUIDs of their requests rather than by ids
+// that only mean something in one database.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"gorm.io/gorm"
+)
+
+// Export formats.
+const (
+	JSONL    = "jsonl"
+	CSV      = "csv"
+	Markdown = "md"
+)
+
+// batchSize is how many requests Write reads at a time.
+const batchSize = 200
+
+// Formats lists the export formats, the importable ones first.
+var Formats = []string{JSONL, CSV, Markdown}
+
+// ValidFormat reports whether format is one of Formats.
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentType is the MIME type of an export in format.
+func ContentType(format string) string {
+	switch format {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case Markdown:
+		return "text/markdown; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// Record is an exported request. Its fields are a stable file format,
+// independent of the database schema.
+type Record struct {
+	UUID            string          `json:"uuid"`
+	SourceName      string          `json:"source_name"`
+	AppName         string          `json:"app_name"`
+	Kind            string          `json:"kind"`
+	Question        string          `json:"question"`
+	Options         []db.Option     `json:"options,omitempty"`
+	DefaultOption   string          `json:"default_option,omitempty"`
+	AllowOther      bool            `json:"allow_other,omitempty"`
+	Schema          json.RawMessage `json:"schema,omitempty"`
+	DefaultResponse string          `json:"default_response,omitempty"`
+	Response        string          `json:"response"`
+	Status          string          `json:"status"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	ExpiresAt       *time.Time      `json:"expires_at,omitempty"`
+	RespondedAt     *time.Time      `json:"responded_at,omitempty"`
+	// ThreadUUID is the UUID of the request that started the thread.
+	ThreadUUID string `json:"thread_uuid,omitempty"`
+	ParentUUID string `json:"parent_uuid,omitempty"`
+}
+
+// Filter selects the requests to export. Since is inclusive, Until
+// exclusive; zero times are ignored. IDs, when set, limits the export to
+// those requests.
+type Filter struct {
+	SourceName string
+	AppName    string
+	Since      time.Time
+	Until      time.Time
+	IDs        []uint
+}
+
+// Write exports the requests matching f to w in format, oldest first.
+func Write(database *gorm.DB, w io.Writer, format string, f Filter) error {
+	var enc encoder
+	switch format {
+	case JSONL:
+		enc = newJSONLEncoder(w)
+	case CSV:
+		enc = newCSVEncoder(w)
+	case Markdown:
+		enc = newMarkdownEncoder(w)
+	default:
+		return fmt.Errorf("unknown export format %q (want %s)", format, strings.Join(Formats, ", "))
+	}
+
+	query := database.Model(&db.Request{}).Order("created_at ASC, id ASC")
+	if f.SourceName != "" {
+		query = query.Where("source_name = ?", f.SourceName)
+	}
+	if f.AppName != "" {
+		query = query.Where("app_name = ?", f.AppName)
+	}
+	if !f.Since.IsZero() {
+		query = query.Where("created_at >= ?", f.Since.UTC())
+	}
+	if !f.Until.IsZero() {
+		query = query.Where("created_at < ?", f.Until.UTC())
+	}
+	if f.IDs != nil {
+		query = query.Where("id IN ?", f.IDs)
+	}
+
+	query = query.Session(&gorm.Session{})
+
+	refs := refCache{database: database, byID: map[uint]ref{}}
+	var last *db.Request
+	for {
+		page := query
+		if last != nil {
+			at := last.CreatedAt.UTC()
+			page = page.Where("(created_at > ? OR (created_at = ? AND id > ?))", at, at, last.ID)
+		}
+		var batch []db.Request
+		if err := page.Limit(batchSize).Find(&batch).Error; err != nil {
+			return fmt.Errorf("failed to read requests: %w", err)
+		}
+		for _, req := range batch {
+			rec, err := refs.record(req)
+			if err != nil {
+				return err
+			}
+			if err := enc.encode(rec, refs.question(req.ParentID)); err != nil {
+				return fmt.Errorf("failed to write export: %w", err)
+			}
+		}
+		if len(batch) < batchSize {
+			return enc.close()
+		}
+		last = &batch[len(batch)-1]
+	}
+}
+
+// encoder writes records in one format. parentQuestion is the question a
+// follow-up answers, or empty.
+type encoder interface {
+	encode(rec Record, parentQuestion string) error
+	close() error
+}
+
+// ref is what an export needs to know about a request another one points
+// at.
+type ref struct {
+	uuid     string
+	question string
+}
+
+// refCache looks up the requests that exported ones point at by id.
+type refCache struct {
+	database *gorm.DB
+	byID     map[uint]ref
+}
+
+func (c refCache) lookup(id uint) (ref, error) {
+	if r, ok := c.byID[id]; ok {
+		return r, nil
+	}
+	var req db.Request
+	err := c.database.Unscoped().Select("id", "uuid", "question").Where("id = ?", id).Limit(1).Find(&req).Error
+	if err != nil {
+		return ref{}, fmt.Errorf("failed to look up request %d: %w", id, err)
+	}
+	r := ref{uuid: req.UUID, question: req.Question}
+	c.byID[id] = r
+	return r, nil
+}
+
+func (c refCache) question(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return c.byID[*id].question
+}
+
+func (c refCache) record(req db.Request) (Record, error) {
+	c.byID[req.ID] = ref{uuid: req.UUID, question: req.Question}
+	rec := Record{
+		UUID:            req.UUID,
+		SourceName:      req.SourceName,
+		AppName:         req.AppName,
+		Kind:            req.Kind,
+		Question:        req.Question,
+		Options:         req.Options,
+		DefaultOption:   req.DefaultOption,
+		AllowOther:      req.AllowOther,
+		Schema:          req.Schema,
+		DefaultResponse: req.DefaultResponse,
+		Response:        req.Response,
+		Status:          req.Status,
+		CreatedAt:       req.CreatedAt.UTC(),
+		UpdatedAt:       req.UpdatedAt.UTC(),
+		ExpiresAt:       utc(req.ExpiresAt),
+		RespondedAt:     utc(req.RespondedAt),
+	}
+	if req.ThreadID != 0 && req.ThreadID != req.ID {
+		thread, err := c.lookup(req.ThreadID)
+		if err != nil {
+			return rec, err
+		}
+		rec.ThreadUUID = thread.uuid
+	}
+	if req.ParentID != nil {
+		parent, err := c.lookup(*req.ParentID)
+		if err != nil {
+			return rec, err
+		}
+		rec.ParentUUID = parent.uuid
+	}
+	return rec, nil
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
@@ -0,0 +1,251 @@
+package export
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB opens an empty database of its own, so a test can export
+// from one and import into another.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	d, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	if err := db.Migrate(d); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := d.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return d
+}
+
+// seed stores a short history: an answered choice, a follow-up to it, a
+// timed-out form from another app and a question nobody answered yet.
+func seed(t *testing.T, d *gorm.DB) []db.Request {
+	t.Helper()
+	base := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	answered := base.Add(2 * time.Minute)
+	requests := []db.Request{
+		{
+			SourceName: "vscode", AppName: "billing", Kind: "choice", Status: "responded",
+			Question:      "Which database?\nWe need one for the invoices.",
+			Options:       []db.Option{{Label: "Postgres", Description: "what ops runs"}, {Label: "SQLite"}},
+			DefaultOption: "Postgres", Response: "Postgres", RespondedAt: &answered,
+		},
+		{SourceName: "vscode", AppName: "billing", Kind: "question", Status: "responded", Question: "Which version?", Response: "16, with \"quotes\",\nand a second line"},
+		{
+			SourceName: "cursor", AppName: "web", Kind: "form", Status: "timed_out",
+			Question: "Deploy settings", Schema: []byte(`{"type":"object"}`),
+			DefaultResponse: `{"replicas":2}`, Response: `{"replicas":2}`,
+		},
+		{SourceName: "cursor", AppName: "web", Kind: "question", Status: "pending", Question: "Ship it?"},
+	}
+	for i := range requests {
+		requests[i].CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		if i == 1 {
+			requests[i].ThreadID = requests[0].ID
+			requests[i].ParentID = &requests[0].ID
+		}
+		if err := d.Create(&requests[i]).Error; err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		if requests[i].ThreadID == 0 {
+			d.Model(&requests[i]).UpdateColumn("thread_id", requests[i].ID)
+		}
+	}
+	return requests
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{JSONL, CSV} {
+		t.Run(format, func(t *testing.T) {
+			src := openTestDB(t)
+			original := seed(t, src)
+
+			var buf bytes.Buffer
+			if err := Write(src, &buf, format, Filter{}); err != nil {
+				t.Fatalf("export failed: %v", err)
+			}
+			records, err := Read(bytes.NewReader(buf.Bytes()), format)
+			if err != nil {
+				t.Fatalf("read failed: %v", err)
+			}
+			if len(records) != len(original) {
+				t.Fatalf("expected %d records, got %d", len(original), len(records))
+			}
+
+			dst := openTestDB(t)
+			res, err := Import(dst, records)
+			if err != nil {
+				t.Fatalf("import failed: %v", err)
+			}
+			if res.Imported != len(original) || res.Skipped != 0 {
+				t.Fatalf("unexpected result %+v", res)
+			}
+
+			var imported []db.Request
+			dst.Order("created_at ASC").Find(&imported)
+			byUUID := map[string]db.Request{}
+			for _, r := range imported {
+				byUUID[r.UUID] = r
+			}
+			for _, want := range original {
+				got, ok := byUUID[want.UUID]
+				if !ok {
+					t.Fatalf("request %s was not imported", want.UUID)
+				}
+				if got.Question != want.Question || got.Response != want.Response || got.Kind != want.Kind {
+					t.Errorf("request %s changed: got %+v", want.UUID, got)
+				}
+				if !got.CreatedAt.Equal(want.CreatedAt) {
+					t.Errorf("request %s created_at = %v, want %v", want.UUID, got.CreatedAt, want.CreatedAt)
+				}
+				if want.Status != "pending" && got.Status != want.Status {
+					t.Errorf("request %s status = %q, want %q", want.UUID, got.Status, want.Status)
+				}
+			}
+
+			first, followUp := byUUID[original[0].UUID], byUUID[original[1].UUID]
+			if first.ThreadID != first.ID {
+				t.Errorf("expected the first request to start its own thread, got thread %d", first.ThreadID)
+			}
+			if followUp.ThreadID != first.ID || followUp.ParentID == nil || *followUp.ParentID != first.ID {
+				t.Errorf("expected the follow-up to be relinked to #%d, got thread %d parent %v", first.ID, followUp.ThreadID, followUp.ParentID)
+			}
+			if first.RespondedAt == nil || !first.RespondedAt.Equal(*original[0].RespondedAt) {
+				t.Errorf("expected responded_at to survive, got %v", first.RespondedAt)
+			}
+			if len(first.Options) != 2 || first.DefaultOption != "Postgres" {
+				t.Errorf("expected options to survive, got %+v", first.Options)
+			}
+			if form := byUUID[original[2].UUID]; string(form.Schema) != `{"type":"object"}` {
+				t.Errorf("expected the schema to survive, got %s", form.Schema)
+			}
+			if got := byUUID[original[3].UUID].Status; got != "orphaned" {
+				t.Errorf("expected an imported pending request to be orphaned, got %q", got)
+			}
+
+			again, err := Import(dst, records)
+			if err != nil {
+				t.Fatalf("second import failed: %v", err)
+			}
+			if again.Imported != 0 || again.Skipped != len(original) {
+				t.Errorf("expected a second import to skip everything, got %+v", again)
+			}
+		})
+	}
+}
+
+func TestWriteFilter(t *testing.T) {
+	d := openTestDB(t)
+	seed(t, d)
+
+	var buf bytes.Buffer
+	since := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	if err := Write(d, &buf, JSONL, Filter{AppName: "billing", Since: since}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	records, _ := Read(&buf, JSONL)
+	if len(records) != 1 || records[0].Question != "Which version?" {
+		t.Fatalf("expected only the billing follow-up, got %+v", records)
+	}
+	if records[0].ThreadUUID == "" || records[0].ThreadUUID != records[0].ParentUUID {
+		t.Errorf("expected the thread to be referenced by UUID, got thread %q parent %q", records[0].ThreadUUID, records[0].ParentUUID)
+	}
+}
+
+func TestImportNormalizesOffsets(t *testing.T) {
+	d := openTestDB(t)
+	est := time.FixedZone("EST", -5*60*60)
+	// 04:00 UTC on February 1st, written from New York, and 02:00 UTC.
+	records := []Record{
+		{UUID: "b8d5f0a2-8f7c-4f0e-9a51-1c2d3e4f5a60", Question: "Later", Status: "responded", CreatedAt: time.Date(2025, 1, 31, 23, 0, 0, 0, est)},
+		{UUID: "0c1d2e3f-4a5b-4c6d-8e7f-8091a2b3c4d5", Question: "Earlier", Status: "responded", CreatedAt: time.Date(2025, 2, 1, 2, 0, 0, 0, time.UTC)},
+	}
+	if _, err := Import(d, records); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	since := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	if err := Write(d, &buf, JSONL, Filter{Since: since}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	got, _ := Read(&buf, JSONL)
+	if len(got) != 2 || got[0].Question != "Earlier" || got[1].Question != "Later" {
+		t.Fatalf("expected both requests in chronological order, got %+v", got)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	d := openTestDB(t)
+	seed(t, d)
+
+	var buf bytes.Buffer
+	if err := Write(d, &buf, Markdown, Filter{}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# Decision log\n",
+		"## Which database?\n",
+		"> We need one for the invoices.",
+		"- Postgres: what ops runs (recommended)",
+		"**Answer:** Postgres",
+		"Follow-up to: Which database?",
+		"**Default answer:**",
+		"\"replicas\": 2",
+		"## Ship it?",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected the decision log to contain %q:\n%s", want, out)
+		}
+	}
+
+	if _, err := Read(strings.NewReader(out), Markdown); err == nil {
+		t.Error("expected Markdown exports to be rejected on import")
+	}
+}
+
+func TestWriteEmptyCSVHasHeader(t *testing.T) {
+	d := openTestDB(t)
+	var buf bytes.Buffer
+	if err := Write(d, &buf, CSV, Filter{}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "uuid,created_at,") {
+		t.Errorf("expected a header row, got %q", buf.String())
+	}
+	records, err := Read(&buf, CSV)
+	if err != nil || len(records) != 0 {
+		t.Errorf("expected no records, got %v, %v", records, err)
+	}
+}
+
+func TestImportRejectsRecordWithoutUUID(t *testing.T) {
+	d := openTestDB(t)
+	_, err := Import(d, []Record{{Question: "Orphan"}})
+	if err == nil {
+		t.Fatal("expected a record without a uuid to be rejected")
+	}
+	var count int64
+	d.Model(&db.Request{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected nothing to be imported, got %d", count)
+	}
+}
@@ -0,0 +1,192 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type jsonlEncoder struct {
+	enc *json.Encoder
+}
+
+func newJSONLEncoder(w io.Writer) *jsonlEncoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &jsonlEncoder{enc: enc}
+}
+
+func (e *jsonlEncoder) encode(rec Record, _ string) error { return e.enc.Encode(rec) }
+func (e *jsonlEncoder) close() error                      { return nil }
+
+// csvColumns are the columns of a CSV export. Options and schema hold
+// JSON; times are RFC 3339 in UTC.
+var csvColumns = []string{
+	"uuid", "created_at", "updated_at", "source_name", "app_name", "kind", "status",
+	"question", "response", "responded_at", "expires_at", "options", "default_option",
+	"allow_other", "schema", "default_response", "thread_uuid", "parent_uuid",
+}
+
+type csvEncoder struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSVEncoder(w io.Writer) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) writeHeader() error {
+	if e.header {
+		return nil
+	}
+	e.header = true
+	return e.w.Write(csvColumns)
+}
+
+func (e *csvEncoder) encode(rec Record, _ string) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	var options string
+	if len(rec.Options) > 0 {
+		b, err := json.Marshal(rec.Options)
+		if err != nil {
+			return err
+		}
+		options = string(b)
+	}
+	return e.w.Write([]string{
+		rec.UUID, formatTime(&rec.CreatedAt), formatTime(&rec.UpdatedAt), rec.SourceName, rec.AppName,
+		rec.Kind, rec.Status, rec.Question, rec.Response, formatTime(rec.RespondedAt),
+		formatTime(rec.ExpiresAt), options, rec.DefaultOption, strconv.FormatBool(rec.AllowOther),
+		string(rec.Schema), rec.DefaultResponse, rec.ThreadUUID, rec.ParentUUID,
+	})
+}
+
+func (e *csvEncoder) close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// markdownEncoder writes a decision log: one section per request, headed
+// by its question, with the answer below.
+type markdownEncoder struct {
+	w     *bufio.Writer
+	count int
+}
+
+func newMarkdownEncoder(w io.Writer) *markdownEncoder {
+	return &markdownEncoder{w: bufio.NewWriter(w)}
+}
+
+func (e *markdownEncoder) encode(rec Record, parentQuestion string) error {
+	if e.count == 0 {
+		e.w.WriteString("# Decision log\n")
+	}
+	e.count++
+
+	title := summary(rec.Question)
+	fmt.Fprintf(e.w, "\n## %s\n\n", title)
+	meta := []string{rec.CreatedAt.UTC().Format("2006-01-02 15:04 UTC"), rec.AppName, rec.SourceName, statusLabel(rec)}
+	fmt.Fprintf(e.w, "_%s_\n", strings.Join(meta, " · "))
+	if parentQuestion != "" {
+		fmt.Fprintf(e.w, "\nFollow-up to: %s\n", summary(parentQuestion))
+	}
+	if strings.TrimSpace(rec.Question) != title {
+		fmt.Fprintf(e.w, "\n%s\n", quote(rec.Question))
+	}
+
+	if len(rec.Options) > 0 {
+		e.w.WriteString("\nOptions:\n\n")
+		for _, o := range rec.Options {
+			line := "- " + o.Label
+			if o.Description != "" {
+				line += ": " + o.Description
+			}
+			if o.Label == rec.DefaultOption {
+				line += " (recommended)"
+			}
+			e.w.WriteString(line + "\n")
+		}
+	}
+
+	if rec.Response == "" {
+		return nil
+	}
+	label := "Answer"
+	if rec.Status == "timed_out" {
+		label = "Default answer"
+	}
+	switch {
+	case rec.Kind == "form":
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, []byte(rec.Response), "", "  ") != nil {
+			pretty.Reset()
+			pretty.WriteString(rec.Response)
+		}
+		fmt.Fprintf(e.w, "\n**%s:**\n\n```json\n%s\n```\n", label, pretty.String())
+	case !strings.Contains(strings.TrimSpace(rec.Response), "\n"):
+		fmt.Fprintf(e.w, "\n**%s:** %s\n", label, strings.TrimSpace(rec.Response))
+	default:
+		fmt.Fprintf(e.w, "\n**%s:**\n\n%s\n", label, quote(rec.Response))
+	}
+	return nil
+}
+
+func (e *markdownEncoder) close() error {
+	if e.count == 0 {
+		e.w.WriteString("# Decision log\n\nNo requests.\n")
+	}
+	return e.w.Flush()
+}
+
+// summary is the first line of s, shortened to fit a heading.
+func summary(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	line = strings.TrimSpace(line)
+	if r := []rune(line); len(r) > 100 {
+		line = string(r[:99]) + "…"
+	}
+	return line
+}
+
+func quote(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight("> "+l, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func statusLabel(rec Record) string {
+	switch rec.Status {
+	case "responded":
+		return "answered"
+	case "timed_out":
+		return "timed out"
+	case "unread":
+		return "notification, unread"
+	case "acknowledged":
+		return "notification"
+	case "pending":
+		return "unanswered"
+	}
+	return strings.ReplaceAll(rec.Status, "_", " ")
+}
@@ -0,0 +1,257 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Read parses an export in format. Markdown exports are for reading and
+// cannot be imported.
+func Read(r io.Reader, format string) ([]Record, error) {
+	switch format {
+	case JSONL:
+		return readJSONL(r)
+	case CSV:
+		return readCSV(r)
+	case Markdown:
+		return nil, fmt.Errorf("markdown exports cannot be imported; use %s or %s", JSONL, CSV)
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+func readJSONL(r io.Reader) ([]Record, error) {
+	dec := json.NewDecoder(r)
+	var records []Record
+	for {
+		var rec Record
+		err := dec.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(records)+1, err)
+		}
+		records = append(records, rec)
+	}
+}
+
+func readCSV(r io.Reader) ([]Record, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	col := map[string]int{}
+	for i, name := range header {
+		col[name] = i
+	}
+	for _, name := range []string{"uuid", "question"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("CSV has no %s column", name)
+		}
+	}
+
+	var records []Record
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+		rec, err := csvRecord(get)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+}
+
+func csvRecord(get func(string) string) (Record, error) {
+	rec := Record{
+		UUID:            get("uuid"),
+		SourceName:      get("source_name"),
+		AppName:         get("app_name"),
+		Kind:            get("kind"),
+		Status:          get("status"),
+		Question:        get("question"),
+		Response:        get("response"),
+		DefaultOption:   get("default_option"),
+		DefaultResponse: get("default_response"),
+		ThreadUUID:      get("thread_uuid"),
+		ParentUUID:      get("parent_uuid"),
+	}
+	if s := get("schema"); s != "" {
+		rec.Schema = json.RawMessage(s)
+	}
+	if s := get("options"); s != "" {
+		if err := json.Unmarshal([]byte(s), &rec.Options); err != nil {
+			return rec, fmt.Errorf("invalid options: %w", err)
+		}
+	}
+	if s := get("allow_other"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return rec, fmt.Errorf("invalid allow_other %q", s)
+		}
+		rec.AllowOther = b
+	}
+	for name, dst := range map[string]*time.Time{"created_at": &rec.CreatedAt, "updated_at": &rec.UpdatedAt} {
+		t, err := parseRecordTime(get(name))
+		if err != nil {
+			return rec, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		if t != nil {
+			*dst = *t
+		}
+	}
+	for name, dst := range map[string]**time.Time{"responded_at": &rec.RespondedAt, "expires_at": &rec.ExpiresAt} {
+		t, err := parseRecordTime(get(name))
+		if err != nil {
+			return rec, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		*dst = t
+	}
+	return rec, nil
+}
+
+func parseRecordTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ImportResult counts what Import did.
+type ImportResult struct {
+	Imported int
+	// Skipped counts records whose UUID the database already has.
+	Skipped int
+}
+
+// Import adds records to the database, skipping those it already has,
+// all or nothing. Threads are relinked by UUID; a follow-up whose thread
+// is not in the database starts a new one. Imported requests still
+// pending are stored as orphaned, since no agent is waiting for them here.
+func Import(database *gorm.DB, records []Record) (ImportResult, error) {
+	var res ImportResult
+	for i, rec := range records {
+		if rec.UUID == "" {
+			return res, fmt.Errorf("record %d has no uuid", i+1)
+		}
+		if rec.Question == "" {
+			return res, fmt.Errorf("record %d (%s) has no question", i+1, rec.UUID)
+		}
+	}
+
+	err := database.Transaction(func(tx *gorm.DB) error {
+		ids := map[string]uint{}
+		lookup := func(uuid string) (uint, error) {
+			if uuid == "" {
+				return 0, nil
+			}
+			if id, ok := ids[uuid]; ok {
+				return id, nil
+			}
+			var req db.Request
+			if err := tx.Unscoped().Select("id").Where("uuid = ?", uuid).Limit(1).Find(&req).Error; err != nil {
+				return 0, fmt.Errorf("failed to look up request %s: %w", uuid, err)
+			}
+			ids[uuid] = req.ID
+			return req.ID, nil
+		}
+
+		for _, rec := range records {
+			existing, err := lookup(rec.UUID)
+			if err != nil {
+				return err
+			}
+			if existing != 0 {
+				res.Skipped++
+				continue
+			}
+
+			req := request(rec)
+			if req.ThreadID, err = lookup(rec.ThreadUUID); err != nil {
+				return err
+			}
+			parent, err := lookup(rec.ParentUUID)
+			if err != nil {
+				return err
+			}
+			if parent != 0 {
+				req.ParentID = &parent
+			}
+			if err := tx.Omit(clause.Associations).Create(&req).Error; err != nil {
+				return fmt.Errorf("failed to import request %s: %w", rec.UUID, err)
+			}
+			if req.ThreadID == 0 {
+				if err := tx.Model(&db.Request{}).Where("id = ?", req.ID).UpdateColumn("thread_id", req.ID).Error; err != nil {
+					return fmt.Errorf("failed to import request %s: %w", rec.UUID, err)
+				}
+			}
+			ids[rec.UUID] = req.ID
+			res.Imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return ImportResult{}, err
+	}
+	return res, nil
+}
+
+func request(rec Record) db.Request {
+	req := db.Request{
+		UUID:            rec.UUID,
+		SourceName:      rec.SourceName,
+		AppName:         rec.AppName,
+		Kind:            rec.Kind,
+		Question:        rec.Question,
+		Options:         rec.Options,
+		DefaultOption:   rec.DefaultOption,
+		AllowOther:      rec.AllowOther,
+		Schema:          rec.Schema,
+		DefaultResponse: rec.DefaultResponse,
+		Response:        rec.Response,
+		Status:          rec.Status,
+		ExpiresAt:       utc(rec.ExpiresAt),
+		RespondedAt:     utc(rec.RespondedAt),
+	}
+	// Stored in UTC like every other time, whatever offset the export
+	// was written with.
+	req.CreatedAt, req.UpdatedAt = rec.CreatedAt.UTC(), rec.UpdatedAt.UTC()
+	if req.UpdatedAt.IsZero() {
+		req.UpdatedAt = req.CreatedAt
+	}
+	if req.Kind == "" {
+		req.Kind = "question"
+	}
+	if req.Status == "" || req.Status == "pending" {
+		req.Status = "orphaned"
+	}
+	return req
+}
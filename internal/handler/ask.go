@@ -2,14 +2,20 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tejzpr/rishvan-mcp/internal/attachment"
 	"github.com/tejzpr/rishvan-mcp/internal/browser"
 	"github.com/tejzpr/rishvan-mcp/internal/config"
 	"github.com/tejzpr/rishvan-mcp/internal/db"
 	"github.com/tejzpr/rishvan-mcp/internal/manager"
+	"github.com/tejzpr/rishvan-mcp/internal/store"
 	"github.com/tejzpr/rishvan-mcp/internal/webserver"
 )
 
@@ -18,6 +24,17 @@ var (
 	browserMu     sync.Mutex
 )
 
+// AskResult is the structured content returned by ask_rishvan. ThreadID
+// and RequestID let the agent ask a follow-up in the same conversation.
+type AskResult struct {
+	Response  string `json:"response"`
+	RequestID uint   `json:"request_id"`
+	ThreadID  uint   `json:"thread_id"`
+}
+
+// resultFunc turns the human's response to req into the tool result.
+type resultFunc func(req *db.Request, response string) *mcp.CallToolResult
+
 func AskRishvan(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	question, err := request.RequireString("question")
 	if err != nil {
@@ -28,64 +45,180 @@ func AskRishvan(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallTool
 		return mcp.NewToolResultError("app_name is required"), nil
 	}
 
-	// Ensure DB is initialized (needed for primary mode)
-	if _, err := db.Init(); err != nil {
-		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	req := &db.Request{
+		SourceName:      config.SourceName,
+		AppName:         appName,
+		Kind:            "question",
+		Question:        question,
+		DefaultResponse: request.GetString("default_response", ""),
+	}
+	if timeout := request.GetInt("timeout_seconds", 0); timeout > 0 {
+		expiresAt := time.Now().Add(time.Duration(timeout) * time.Second)
+		req.ExpiresAt = &expiresAt
+	} else if timeout < 0 {
+		return mcp.NewToolResultError("timeout_seconds must be positive"), nil
+	}
+	if id := request.GetInt("thread_id", 0); id > 0 {
+		req.ThreadID = uint(id)
+	} else if id < 0 {
+		return mcp.NewToolResultError("thread_id must be positive"), nil
 	}
+	if id := request.GetInt("parent_id", 0); id > 0 {
+		parent := uint(id)
+		req.ParentID = &parent
+	} else if id < 0 {
+		return mcp.NewToolResultError("parent_id must be positive"), nil
+	}
+	if req.Attachments, err = parseAttachments(request.GetArguments()["attachments"]); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return ask(ctx, req, textResult)
+}
+
+// textResult returns the response as text, followed by the ids to pass
+// back for a follow-up question, and as AskResult structured content.
+func textResult(req *db.Request, response string) *mcp.CallToolResult {
+	result := mcp.NewToolResultStructured(AskResult{
+		Response:  response,
+		RequestID: req.ID,
+		ThreadID:  req.ThreadID,
+	}, response)
+	result.Content = append(result.Content,
+		mcp.NewTextContent(fmt.Sprintf("request_id: %d, thread_id: %d", req.ID, req.ThreadID)))
+	return result
+}
 
-	// Start web server (or detect existing one)
+// startServer starts the web server, opening the database, or detects
+// the primary that already serves it.
+func startServer() error {
 	if err := webserver.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start web server: %w", err)
+		return fmt.Errorf("failed to start web server: %w", err)
 	}
+	return nil
+}
 
-	// Open browser on first invocation
+// openBrowserOnce opens the web UI the first time this process needs the
+// human's attention.
+func openBrowserOnce() {
 	browserMu.Lock()
-	if !browserOpened {
-		browserOpened = true
-		browserMu.Unlock()
-		_ = browser.Open(webserver.BaseURL)
-	} else {
+	if browserOpened {
 		browserMu.Unlock()
+		return
 	}
+	browserOpened = true
+	browserMu.Unlock()
 
-	if webserver.IsPrimary {
-		return askLocal(ctx, appName, question)
+	// A one-time link logs the browser in to the web UI.
+	url, err := webserver.LoginURL()
+	if err != nil {
+		log.Printf("rishvan-mcp: failed to create login link: %v", err)
+		url = webserver.PrimaryURL()
 	}
-	return askRemote(ctx, appName, question)
+	_ = browser.Open(url)
+}
+
+// ask makes sure the web UI is reachable, then hands req to the local
+// manager or to the primary instance and waits for the human. Messages the
+// human left for this agent in the meantime are appended to the result,
+// after any files attached to the answer.
+func ask(ctx context.Context, req *db.Request, result resultFunc) (*mcp.CallToolResult, error) {
+	req.OwnerPID = os.Getpid()
+
+	if err := startServer(); err != nil {
+		return nil, err
+	}
+	openBrowserOnce()
+
+	var res *mcp.CallToolResult
+	var err error
+	if webserver.IsPrimary() {
+		res, err = askLocal(ctx, req, result)
+	} else {
+		res, err = askRemote(ctx, req, result)
+	}
+	if err == nil && res != nil && !res.IsError {
+		appendResponseAttachments(res, req.ID)
+		appendMessages(res, req.SourceName, req.AppName)
+	}
+	return res, err
 }
 
 // askLocal handles the request in-process (primary server mode).
-func askLocal(ctx context.Context, appName, question string) (*mcp.CallToolResult, error) {
-	reqID, ch, err := manager.Instance.CreateRequest(config.SourceName, appName, question)
+func askLocal(ctx context.Context, req *db.Request, result resultFunc) (*mcp.CallToolResult, error) {
+	ch, err := manager.Instance.Create(req)
+	if errors.Is(err, manager.ErrUnknownThread) || errors.Is(err, attachment.ErrInvalid) {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Notify frontend via SSE
-	manager.Broker.Publish(reqID, config.SourceName, appName, question)
+	manager.Broker.Publish(req.ID, req.SourceName, req.AppName, req.Question)
+
+	return waitLocal(ctx, req, ch, result)
+}
 
-	// Block until human responds or context is cancelled
+// waitLocal blocks until the human responds on ch or ctx is cancelled.
+func waitLocal(ctx context.Context, req *db.Request, ch <-chan string, result resultFunc) (*mcp.CallToolResult, error) {
 	select {
 	case <-ctx.Done():
+		_ = manager.Instance.CancelRequest(req.ID)
 		return nil, ctx.Err()
 	case response, ok := <-ch:
 		if !ok {
-			return mcp.NewToolResultError("request channel closed unexpectedly"), nil
+			return closedResult(req), nil
 		}
-		return mcp.NewToolResultText(response), nil
+		return result(req, response), nil
 	}
 }
 
 // askRemote delegates to the primary rishvan-mcp server via HTTP.
-func askRemote(ctx context.Context, appName, question string) (*mcp.CallToolResult, error) {
-	reqID, err := webserver.RemoteCreateRequest(config.SourceName, appName, question)
+func askRemote(ctx context.Context, req *db.Request, result resultFunc) (*mcp.CallToolResult, error) {
+	reqID, err := webserver.RemoteCreateRequest(req)
+	if errors.Is(err, manager.ErrUnknownThread) || errors.Is(err, attachment.ErrInvalid) {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err != nil && webserver.Promote() {
+		// The primary exited since we last looked; serve it ourselves.
+		return askLocal(ctx, req, result)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create remote request: %w", err)
 	}
+	req.ID = reqID
 
 	response, err := webserver.RemotePollResponse(ctx, reqID)
+	for errors.Is(err, webserver.ErrPrimaryGone) {
+		// The primary exited. Whoever rebinds the port first becomes the
+		// new primary and takes this request over; everyone else keeps
+		// waiting on the winner.
+		if webserver.Promote() {
+			ch, err := manager.Instance.Attach(reqID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to take over request: %w", err)
+			}
+			return waitLocal(ctx, req, ch, result)
+		}
+		response, err = webserver.RemotePollResponse(ctx, reqID)
+	}
+	if errors.Is(err, webserver.ErrTimedOut) {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if ctx.Err() != nil {
+		_ = webserver.RemoteCancelRequest(reqID)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return mcp.NewToolResultText(response), nil
+	return result(req, response), nil
+}
+
+// closedResult explains why a request's channel was closed without a
+// response.
+func closedResult(req *db.Request) *mcp.CallToolResult {
+	if stored, err := store.Get().Get(req.ID); err == nil && stored.Status == "timed_out" {
+		return mcp.NewToolResultError(webserver.ErrTimedOut.Error())
+	}
+	return mcp.NewToolResultError("request channel closed unexpectedly")
 }
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tejzpr/rishvan-mcp/internal/attachment"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/webserver"
+)
+
+// parseAttachments accepts, for each attachment, either a plain
+// {name, mime_type, data|text} object with base64 data, or an MCP embedded
+// resource {type: "resource", resource: {uri, mimeType, blob|text}}. MCP
+// image content {type: "image", data, mimeType} works as the plain form.
+func parseAttachments(raw any) ([]db.Attachment, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("attachments must be an array")
+	}
+
+	atts := make([]db.Attachment, 0, len(items))
+	for i, item := range items {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("attachment %d must be an object", i)
+		}
+		if res, ok := obj["resource"].(map[string]any); ok {
+			obj = map[string]any{
+				"name":      path.Base(str(res, "uri")),
+				"mime_type": res["mimeType"],
+				"data":      res["blob"],
+				"text":      res["text"],
+			}
+		}
+
+		a := db.Attachment{Name: str(obj, "name"), MIMEType: str(obj, "mime_type")}
+		if a.MIMEType == "" {
+			a.MIMEType = str(obj, "mimeType")
+		}
+		if a.MIMEType == "" {
+			return nil, fmt.Errorf("attachment %d has no mime_type", i)
+		}
+		switch data, text := str(obj, "data"), str(obj, "text"); {
+		case data != "":
+			b, err := base64.StdEncoding.DecodeString(data)
+			if err != nil {
+				return nil, fmt.Errorf("attachment %d: data is not valid base64", i)
+			}
+			a.Data = b
+		case text != "":
+			a.Data = []byte(text)
+		default:
+			return nil, fmt.Errorf("attachment %d has no data or text", i)
+		}
+		atts = append(atts, a)
+	}
+	if err := attachment.Validate(atts); err != nil {
+		return nil, err
+	}
+	return atts, nil
+}
+
+func str(obj map[string]any, key string) string {
+	s, _ := obj[key].(string)
+	return s
+}
+
+// appendResponseAttachments adds the files the human attached to their
+// answer to request reqID to res: images as image content, anything else
+// as an embedded resource.
+func appendResponseAttachments(res *mcp.CallToolResult, reqID uint) {
+	atts, err := responseAttachments(reqID)
+	if err != nil {
+		log.Printf("rishvan-mcp: failed to fetch attachments of request %d: %v", reqID, err)
+		return
+	}
+	if len(atts) == 0 {
+		return
+	}
+	names := make([]string, len(atts))
+	for i, a := range atts {
+		names[i] = a.Name
+	}
+	res.Content = append(res.Content, mcp.NewTextContent("The human attached: "+strings.Join(names, ", ")))
+	for _, a := range atts {
+		res.Content = append(res.Content, attachmentContent(reqID, a))
+	}
+}
+
+// responseAttachments loads the human's attachments of request reqID with
+// their content, from the local database or from the primary.
+func responseAttachments(reqID uint) ([]db.Attachment, error) {
+	if webserver.IsPrimary() {
+		database := db.Get()
+		var atts []db.Attachment
+		if err := database.Where("request_id = ? AND from_human = ?", reqID, true).Order("idx").Find(&atts).Error; err != nil {
+			return nil, err
+		}
+		for i := range atts {
+			data, err := attachment.Read(database, &atts[i])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", atts[i].Name, err)
+			}
+			atts[i].Data = data
+		}
+		return atts, nil
+	}
+
+	req, err := webserver.RemoteGetRequest(reqID)
+	if err != nil {
+		return nil, err
+	}
+	var atts []db.Attachment
+	for _, a := range req.Attachments {
+		if !a.FromHuman {
+			continue
+		}
+		if a.Data, err = webserver.RemoteAttachment(reqID, a.Index); err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", a.Name, err)
+		}
+		atts = append(atts, a)
+	}
+	return atts, nil
+}
+
+func attachmentContent(reqID uint, a db.Attachment) mcp.Content {
+	if attachment.IsImage(a.MIMEType) {
+		return mcp.NewImageContent(base64.StdEncoding.EncodeToString(a.Data), a.MIMEType)
+	}
+	uri := fmt.Sprintf("%s/api/requests/%d/attachments/%d", webserver.PrimaryURL(), reqID, a.Index)
+	if strings.HasPrefix(a.MIMEType, "text/") || a.MIMEType == "application/json" {
+		return mcp.NewEmbeddedResource(mcp.TextResourceContents{URI: uri, MIMEType: a.MIMEType, Text: string(a.Data)})
+	}
+	return mcp.NewEmbeddedResource(mcp.BlobResourceContents{URI: uri, MIMEType: a.MIMEType, Blob: base64.StdEncoding.EncodeToString(a.Data)})
+}
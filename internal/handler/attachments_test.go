@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/tejzpr/rishvan-mcp/internal/attachment"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+)
+
+// png is the smallest valid PNG signature http.DetectContentType accepts.
+var png = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestParseAttachments(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(png)
+	tests := []struct {
+		name string
+		raw  any
+		want []db.Attachment
+	}{
+		{"none", nil, nil},
+		{"plain with data", []any{map[string]any{"name": "shot.png", "mime_type": "image/png", "data": encoded}},
+			[]db.Attachment{{Name: "shot.png", MIMEType: "image/png", Data: png}}},
+		{"plain with text", []any{map[string]any{"name": "fix.diff", "mime_type": "text/x-diff", "text": "-a\n+b\n"}},
+			[]db.Attachment{{Name: "fix.diff", MIMEType: "text/x-diff", Data: []byte("-a\n+b\n")}}},
+		{"image content", []any{map[string]any{"type": "image", "mimeType": "image/png", "data": encoded}},
+			[]db.Attachment{{MIMEType: "image/png", Data: png}}},
+		{"embedded blob", []any{map[string]any{"type": "resource", "resource": map[string]any{
+			"uri": "file:///tmp/shot.png", "mimeType": "image/png", "blob": encoded}}},
+			[]db.Attachment{{Name: "shot.png", MIMEType: "image/png", Data: png}}},
+		{"embedded text", []any{map[string]any{"type": "resource", "resource": map[string]any{
+			"uri": "file:///var/log/build.log", "mimeType": "text/plain", "text": "ok\n"}}},
+			[]db.Attachment{{Name: "build.log", MIMEType: "text/plain", Data: []byte("ok\n")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAttachments(tt.raw)
+			if err != nil {
+				t.Fatalf("parseAttachments failed: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d attachments, want %d", len(got), len(tt.want))
+			}
+			for i, a := range got {
+				w := tt.want[i]
+				if a.Name != w.Name || a.MIMEType != w.MIMEType || string(a.Data) != string(w.Data) {
+					t.Errorf("attachment %d: got %+v, want %+v", i, a, w)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAttachmentsRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     any
+		invalid bool
+	}{
+		{"not an array", map[string]any{"data": "x"}, false},
+		{"not an object", []any{"shot.png"}, false},
+		{"no type", []any{map[string]any{"text": "x"}}, false},
+		{"bad base64", []any{map[string]any{"mime_type": "image/png", "data": "not base64!"}}, false},
+		{"no content", []any{map[string]any{"mime_type": "text/plain"}}, false},
+		{"disallowed type", []any{map[string]any{"mime_type": "text/html", "text": "<script>"}}, true},
+		{"fake image", []any{map[string]any{"mime_type": "image/png", "text": "<html>"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseAttachments(tt.raw)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if errors.Is(err, attachment.ErrInvalid) != tt.invalid {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/manager"
+)
+
+// ChoiceResult is the structured content returned by ask_rishvan_choice.
+type ChoiceResult struct {
+	Choice string `json:"choice"`
+	// Index is the position of the chosen option, or -1 for a free-text
+	// "other" answer.
+	Index int  `json:"index"`
+	Other bool `json:"other"`
+}
+
+func AskRishvanChoice(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	question, err := request.RequireString("question")
+	if err != nil {
+		return mcp.NewToolResultError("question is required"), nil
+	}
+	appName, err := request.RequireString("app_name")
+	if err != nil {
+		return mcp.NewToolResultError("app_name is required"), nil
+	}
+	options, err := parseOptions(request.GetArguments()["options"])
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	req := &db.Request{
+		SourceName:    config.SourceName,
+		AppName:       appName,
+		Kind:          "choice",
+		Question:      question,
+		Options:       options,
+		DefaultOption: request.GetString("default", ""),
+		AllowOther:    request.GetBool("allow_other", false),
+	}
+	if req.DefaultOption != "" && manager.OptionIndex(req, req.DefaultOption) < 0 {
+		return mcp.NewToolResultError("default must be the label of one of the options"), nil
+	}
+	return ask(ctx, req, choiceResult)
+}
+
+func choiceResult(req *db.Request, response string) *mcp.CallToolResult {
+	idx := manager.OptionIndex(req, response)
+	return mcp.NewToolResultStructured(ChoiceResult{
+		Choice: response,
+		Index:  idx,
+		Other:  idx < 0,
+	}, response)
+}
+
+// parseOptions accepts either plain strings or {label, description}
+// objects for each option.
+func parseOptions(raw any) ([]db.Option, error) {
+	items, ok := raw.([]any)
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("options must be a non-empty array")
+	}
+
+	options := make([]db.Option, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	for i, item := range items {
+		var opt db.Option
+		switch v := item.(type) {
+		case string:
+			opt.Label = v
+		case map[string]any:
+			opt.Label, _ = v["label"].(string)
+			opt.Description, _ = v["description"].(string)
+		}
+		if opt.Label == "" {
+			return nil, fmt.Errorf("option %d has no label", i)
+		}
+		if seen[opt.Label] {
+			return nil, fmt.Errorf("duplicate option %q", opt.Label)
+		}
+		seen[opt.Label] = true
+		options = append(options, opt)
+	}
+	return options, nil
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  any
+		want []db.Option
+		ok   bool
+	}{
+		{"strings", []any{"Yes", "No"}, []db.Option{{Label: "Yes"}, {Label: "No"}}, true},
+		{"objects", []any{map[string]any{"label": "Postgres", "description": "managed"}, "SQLite"},
+			[]db.Option{{Label: "Postgres", Description: "managed"}, {Label: "SQLite"}}, true},
+		{"missing", nil, nil, false},
+		{"not an array", "Yes", nil, false},
+		{"empty", []any{}, nil, false},
+		{"no label", []any{map[string]any{"description": "what?"}}, nil, false},
+		{"empty label", []any{""}, nil, false},
+		{"wrong type", []any{42}, nil, false},
+		{"duplicate", []any{"Yes", map[string]any{"label": "Yes"}}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOptions(tt.raw)
+			if !tt.ok {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseOptions failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/schema"
+)
+
+func AskRishvanForm(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	question, err := request.RequireString("question")
+	if err != nil {
+		return mcp.NewToolResultError("question is required"), nil
+	}
+	appName, err := request.RequireString("app_name")
+	if err != nil {
+		return mcp.NewToolResultError("app_name is required"), nil
+	}
+
+	// The schema may arrive as an object or as a JSON-encoded string.
+	var raw []byte
+	switch v := request.GetArguments()["schema"].(type) {
+	case string:
+		raw = []byte(v)
+	case map[string]any:
+		raw, _ = json.Marshal(v)
+	default:
+		return mcp.NewToolResultError("schema is required"), nil
+	}
+	if _, err := schema.Parse(raw); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	req := &db.Request{
+		SourceName: config.SourceName,
+		AppName:    appName,
+		Kind:       "form",
+		Question:   question,
+		Schema:     raw,
+	}
+	return ask(ctx, req, formResult)
+}
+
+func formResult(_ *db.Request, response string) *mcp.CallToolResult {
+	var data map[string]any
+	if err := json.Unmarshal([]byte(response), &data); err != nil {
+		return mcp.NewToolResultError("form response is not a JSON object")
+	}
+	return mcp.NewToolResultStructured(data, response)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/manager"
+	"github.com/tejzpr/rishvan-mcp/internal/webserver"
+)
+
+// MessagesResult is the structured content returned by
+// check_rishvan_messages.
+type MessagesResult struct {
+	Messages []MessageItem `json:"messages"`
+}
+
+// MessageItem is one message from the human.
+type MessageItem struct {
+	ID     uint      `json:"id"`
+	Body   string    `json:"body"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+func CheckRishvanMessages(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	appName, err := request.RequireString("app_name")
+	if err != nil {
+		return mcp.NewToolResultError("app_name is required"), nil
+	}
+	if err := startServer(); err != nil {
+		return nil, err
+	}
+
+	messages, err := takeMessages(config.SourceName, appName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check messages: %w", err)
+	}
+	if len(messages) == 0 {
+		return mcp.NewToolResultStructured(MessagesResult{Messages: []MessageItem{}}, "No new messages."), nil
+	}
+
+	items := make([]MessageItem, len(messages))
+	for i, m := range messages {
+		items[i] = MessageItem{ID: m.ID, Body: m.Body, SentAt: m.CreatedAt}
+	}
+	res := mcp.NewToolResultStructured(MessagesResult{Messages: items}, "")
+	res.Content = messageContent(messages)
+	return res, nil
+}
+
+// takeMessages receives the unread messages for this agent from the local
+// database or from the primary.
+func takeMessages(sourceName, appName string) ([]db.Message, error) {
+	if webserver.IsPrimary() {
+		return manager.TakeMessages(sourceName, appName)
+	}
+	messages, err := webserver.RemoteTakeMessages(sourceName, appName)
+	if err != nil && webserver.Promote() {
+		return manager.TakeMessages(sourceName, appName)
+	}
+	return messages, err
+}
+
+// appendMessages adds any unread messages for the asking agent to res, so
+// the human's notes reach it without a separate check.
+func appendMessages(res *mcp.CallToolResult, sourceName, appName string) {
+	messages, err := takeMessages(sourceName, appName)
+	if err != nil {
+		log.Printf("rishvan-mcp: failed to fetch messages: %v", err)
+		return
+	}
+	res.Content = append(res.Content, messageContent(messages)...)
+}
+
+func messageContent(messages []db.Message) []mcp.Content {
+	content := make([]mcp.Content, len(messages))
+	for i, m := range messages {
+		content[i] = mcp.NewTextContent(fmt.Sprintf("Message from the human (sent %s): %s",
+			m.CreatedAt.Format(time.RFC3339), m.Body))
+	}
+	return content
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/manager"
+	"github.com/tejzpr/rishvan-mcp/internal/webserver"
+)
+
+// NotifyResult is the structured content returned by notify_rishvan.
+type NotifyResult struct {
+	RequestID uint `json:"request_id"`
+}
+
+// NotifyRishvan records an informational entry for the human and returns
+// without waiting for it to be read.
+func NotifyRishvan(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	message, err := request.RequireString("message")
+	if err != nil {
+		return mcp.NewToolResultError("message is required"), nil
+	}
+	appName, err := request.RequireString("app_name")
+	if err != nil {
+		return mcp.NewToolResultError("app_name is required"), nil
+	}
+	if links := request.GetStringSlice("links", nil); len(links) > 0 {
+		message = strings.TrimRight(message, "\n") + "\n\nLinks:\n- " + strings.Join(links, "\n- ")
+	}
+
+	req := &db.Request{
+		SourceName: config.SourceName,
+		AppName:    appName,
+		Kind:       "notification",
+		Question:   message,
+		OwnerPID:   os.Getpid(),
+	}
+	if req.Attachments, err = parseAttachments(request.GetArguments()["attachments"]); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if err := startServer(); err != nil {
+		return nil, err
+	}
+	openBrowserOnce()
+
+	if err := notify(req); err != nil {
+		return nil, fmt.Errorf("failed to record notification: %w", err)
+	}
+
+	res := mcp.NewToolResultStructured(NotifyResult{RequestID: req.ID},
+		fmt.Sprintf("Notification #%d recorded. The human has not necessarily seen it yet.", req.ID))
+	appendMessages(res, req.SourceName, req.AppName)
+	return res, nil
+}
+
+// notify stores req locally or through the primary instance.
+func notify(req *db.Request) error {
+	if webserver.IsPrimary() {
+		return manager.Instance.Notify(req)
+	}
+	id, err := webserver.RemoteCreateRequest(req)
+	if err != nil && webserver.Promote() {
+		return manager.Instance.Notify(req)
+	}
+	if err != nil {
+		return err
+	}
+	req.ID = id
+	return nil
+}
@@ -0,0 +1,33 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ClosedError is returned when answering a request that nobody is waiting
+// on any more.
+type ClosedError struct {
+	ID     uint
+	Status string
+}
+
+func (e *ClosedError) Error() string {
+	switch e.Status {
+	case "cancelled":
+		return fmt.Sprintf("request %d was cancelled: the agent stopped waiting for an answer", e.ID)
+	case "timed_out":
+		return fmt.Sprintf("request %d timed out before it was answered", e.ID)
+	case "orphaned":
+		return fmt.Sprintf("request %d is orphaned: the agent that asked it is no longer running, so an answer cannot be delivered", e.ID)
+	}
+	return fmt.Sprintf("request %d is %s", e.ID, e.Status)
+}
+
+// ErrPending is returned when deleting a request an agent is still
+// waiting for.
+var ErrPending = errors.New("request is still pending; cancel it or answer it first")
+
+// ErrUnknownThread is returned when a request names a thread or parent
+// request that does not exist.
+var ErrUnknownThread = errors.New("unknown thread")
@@ -1,16 +1,26 @@
 package manager
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/tejzpr/rishvan-mcp/internal/attachment"
 	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/process"
+	"github.com/tejzpr/rishvan-mcp/internal/store"
+	"gorm.io/gorm"
 )
 
 type RequestManager struct {
 	mu       sync.Mutex
 	channels map[uint]chan string
+	// done holds one channel per watched request, closed when the request
+	// leaves the pending state. See Done.
+	done map[uint]chan struct{}
+	// timers times out the pending requests that have a deadline.
+	timers map[uint]*time.Timer
 }
 
 var Instance = NewRequestManager()
@@ -19,63 +29,326 @@ var Instance = NewRequestManager()
 func NewRequestManager() *RequestManager {
 	return &RequestManager{
 		channels: make(map[uint]chan string),
+		done:     make(map[uint]chan struct{}),
+		timers:   make(map[uint]*time.Timer),
 	}
 }
 
 func (m *RequestManager) CreateRequest(sourceName, appName, question string) (uint, <-chan string, error) {
-	database := db.Get()
-	if database == nil {
-		return 0, nil, fmt.Errorf("database not initialized")
-	}
-
 	req := db.Request{
 		SourceName: sourceName,
 		AppName:    appName,
 		Question:   question,
-		Status:     "pending",
 	}
-	if err := database.Create(&req).Error; err != nil {
-		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	ch, err := m.Create(&req)
+	if err != nil {
+		return 0, nil, err
+	}
+	return req.ID, ch, nil
+}
+
+// Create stores req as a pending request and returns the channel its
+// response will be delivered on. Kind defaults to "question". The contents
+// of req.Attachments are validated and written to disk; attachment errors
+// wrap attachment.ErrInvalid.
+func (m *RequestManager) Create(req *db.Request) (<-chan string, error) {
+	requests, err := requestStore()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Kind == "" {
+		req.Kind = "question"
+	}
+	if err := attachment.Validate(req.Attachments); err != nil {
+		return nil, err
+	}
+	// Agents reach the primary of their own host, so its host is theirs.
+	if req.OwnerHost, err = process.HostID(); err != nil {
+		return nil, err
 	}
 
+	// An agent restarted since asking gets its orphaned request back, so
+	// the human keeps seeing a single entry for the question, attachments
+	// included.
+	if reclaimed, err := m.reclaim(requests, req); err != nil || reclaimed {
+		if err != nil {
+			return nil, err
+		}
+		return m.track(req), nil
+	}
+
+	if err := linkThread(requests, req); err != nil {
+		return nil, err
+	}
+	req.Status = "pending"
+	if err := insert(requests, req); err != nil {
+		return nil, err
+	}
+
+	return m.track(req), nil
+}
+
+// requestStore returns the store requests are kept in.
+func requestStore() (store.RequestStore, error) {
+	s := store.Get()
+	if s == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return s, nil
+}
+
+// insert stores req with its attachments. Nothing is left behind on
+// failure.
+func insert(requests store.RequestStore, req *db.Request) error {
+	files := req.Attachments
+	database := db.Get()
+	if len(files) > 0 && database == nil {
+		return fmt.Errorf("attachments need the database, which is not initialized")
+	}
+	if err := requests.Create(req); err != nil {
+		return err
+	}
+	if len(files) > 0 {
+		saved, err := attachment.Save(database, req.ID, files)
+		if err != nil {
+			attachment.RemoveAll(database, req.ID)
+			// Purge never deletes a pending request.
+			if req.Status == "pending" {
+				requests.Cancel(req.ID)
+			}
+			requests.Purge([]uint{req.ID})
+			return err
+		}
+		req.Attachments = saved
+	}
+	return nil
+}
+
+// Attach registers a response channel for an existing request, for
+// instance one created through a primary that has since exited. If the
+// request was already answered the channel carries that answer; if it is
+// otherwise closed the channel is closed.
+func (m *RequestManager) Attach(id uint) (<-chan string, error) {
+	requests, err := requestStore()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := requests.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("request %d not found", id)
+	}
+
+	if req.Status == "pending" {
+		return m.track(req), nil
+	}
+
+	// Replay what the original waiter would have received.
+	ch := make(chan string, 1)
+	if (req.Status == "responded" || req.Status == "timed_out") && req.Response != "" {
+		ch <- req.Response
+	}
+	close(ch)
+	return ch, nil
+}
+
+// track creates the response channel for a pending request and arms its
+// timeout, if any.
+func (m *RequestManager) track(req *db.Request) <-chan string {
 	ch := make(chan string, 1)
 	m.mu.Lock()
 	m.channels[req.ID] = ch
 	m.mu.Unlock()
 
-	return req.ID, ch, nil
+	m.arm(req)
+	return ch
 }
 
-func (m *RequestManager) RespondToRequest(id uint, response string) error {
-	database := db.Get()
-	if database == nil {
-		return fmt.Errorf("database not initialized")
+// arm starts the timer that times out req at its deadline, if it has
+// one. The timer is stopped when the request closes earlier.
+func (m *RequestManager) arm(req *db.Request) {
+	if req.ExpiresAt == nil {
+		return
 	}
+	id := req.ID
 
-	now := time.Now()
-	result := database.Model(&db.Request{}).Where("id = ? AND status = ?", id, "pending").Updates(map[string]interface{}{
-		"response":     response,
-		"status":       "responded",
-		"responded_at": &now,
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.timers[id]; ok {
+		old.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(*req.ExpiresAt), func() {
+		m.mu.Lock()
+		if m.timers[id] == timer {
+			delete(m.timers, id)
+		}
+		m.mu.Unlock()
+		_ = m.TimeoutRequest(id)
 	})
-	if result.Error != nil {
-		return fmt.Errorf("failed to update request: %w", result.Error)
+	m.timers[id] = timer
+}
+
+func (m *RequestManager) RespondToRequest(id uint, response string) error {
+	return m.RespondWithAttachments(id, response, nil)
+}
+
+// RespondWithAttachments answers request id with response and files the
+// human attached to it. The files are stored along with the answer, so a
+// waiting agent always finds them, and removed again if it is refused.
+func (m *RequestManager) RespondWithAttachments(id uint, response string, atts []db.Attachment) error {
+	requests, err := requestStore()
+	if err != nil {
+		return err
 	}
-	if result.RowsAffected == 0 {
+	if err := attachment.Validate(atts); err != nil {
+		return err
+	}
+
+	req, err := requests.Get(id)
+	if err != nil {
 		return fmt.Errorf("request %d not found or already responded", id)
 	}
+	if req.Status == "cancelled" || req.Status == "timed_out" || req.Status == "orphaned" {
+		return &ClosedError{ID: id, Status: req.Status}
+	}
+	if err := validateResponse(req, response); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var saved []db.Attachment
+	if len(atts) == 0 {
+		err = requests.Respond(id, response, now)
+	} else {
+		if req.Status != "pending" {
+			return fmt.Errorf("request %d not found or already responded", id)
+		}
+		database := db.Get()
+		if database == nil {
+			return fmt.Errorf("attachments need the database, which is not initialized")
+		}
+		for i := range atts {
+			atts[i].FromHuman = true
+		}
+		// The request is claimed and its files stored in one transaction:
+		// a concurrent answer then either waits or finds it answered, and
+		// an answer that fails leaves no rows behind.
+		err = database.Transaction(func(tx *gorm.DB) error {
+			if err := store.NewSQL(tx).Respond(id, response, now); err != nil {
+				return err
+			}
+			var err error
+			saved, err = attachment.Save(tx, id, atts)
+			return err
+		})
+		if err != nil {
+			attachment.Discard(saved)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return fmt.Errorf("request %d not found or already responded", id)
+		}
+		return err
+	}
+
+	m.resolve(id, response)
+	Broker.PublishEvent("request-responded", id)
+	return nil
+}
+
+// TimeoutRequest marks a still-pending request as timed_out. The waiting
+// channel receives the request's default response, or is closed without a
+// value when there is none.
+func (m *RequestManager) TimeoutRequest(id uint) error {
+	requests, err := requestStore()
+	if err != nil {
+		return err
+	}
+
+	req, err := requests.Get(id)
+	if err != nil {
+		return fmt.Errorf("request %d not found", id)
+	}
 
+	if err := requests.Timeout(id, req.DefaultResponse); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return fmt.Errorf("request %d is no longer pending", id)
+		}
+		return err
+	}
+
+	m.resolve(id, req.DefaultResponse)
+	Broker.PublishEvent("request-timed-out", id)
+	return nil
+}
+
+// CancelRequest marks a still-pending request as cancelled because its
+// agent stopped waiting, and drops its channel.
+func (m *RequestManager) CancelRequest(id uint) error {
+	requests, err := requestStore()
+	if err != nil {
+		return err
+	}
+
+	if err := requests.Cancel(id); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return fmt.Errorf("request %d not found or no longer pending", id)
+		}
+		return err
+	}
+
+	m.resolve(id, "")
+	Broker.PublishEvent("request-cancelled", id)
+	return nil
+}
+
+// Done returns a channel that is closed once request id is responded to,
+// times out, is cancelled, orphaned or deleted through this manager. Any
+// number of callers may wait on it, unlike the single response channel
+// returned by Create. Callers should check the stored status after
+// calling Done, since a request that already left the pending state never
+// closes it.
+func (m *RequestManager) Done(id uint) <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	done, ok := m.done[id]
+	if !ok {
+		done = make(chan struct{})
+		m.done[id] = done
+	}
+	return done
+}
+
+// resolve delivers response to the waiter of request id, if any, and
+// forgets its channel and timer. An empty response closes the channel
+// without a value. It reports whether anything was watching id.
+func (m *RequestManager) resolve(id uint, response string) bool {
 	m.mu.Lock()
+	timer, timed := m.timers[id]
+	if timed {
+		timer.Stop()
+		delete(m.timers, id)
+	}
 	ch, ok := m.channels[id]
 	if ok {
 		delete(m.channels, id)
 	}
+	done, watched := m.done[id]
+	if watched {
+		delete(m.done, id)
+		close(done)
+	}
 	m.mu.Unlock()
 
 	if ok {
-		ch <- response
+		if response != "" {
+			ch <- response
+		}
 		close(ch)
 	}
-
-	return nil
+	return timed || ok || watched
 }
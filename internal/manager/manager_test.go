@@ -1,12 +1,18 @@
 package manager
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/tejzpr/rishvan-mcp/internal/config"
 	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/store"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -20,16 +26,36 @@ func setupTestDB(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to open test db: %v", err)
 	}
-	if err := d.AutoMigrate(&db.Request{}); err != nil {
+	if err := db.Migrate(d); err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
 	db.InitWithDB(d)
+	useTestDataDir(t)
+}
+
+// useTestDataDir keeps attachments and archives written or deleted by a
+// test out of the real data directory.
+func useTestDataDir(t *testing.T) {
+	t.Helper()
+	oldDir := config.DataDir
+	config.DataDir = t.TempDir()
+	t.Cleanup(func() { config.DataDir = oldDir })
+}
+
+// setupMemoryStore runs the manager on an in-memory store, without a
+// database.
+func setupMemoryStore(t *testing.T) *store.Memory {
+	t.Helper()
+	s := store.NewMemory()
+	db.InitWithDB(nil)
+	store.InitWithStore(s)
+	t.Cleanup(func() { store.InitWithStore(nil) })
+	useTestDataDir(t)
+	return s
 }
 
 func newTestManager() *RequestManager {
-	return &RequestManager{
-		channels: make(map[uint]chan string),
-	}
+	return NewRequestManager()
 }
 
 func TestCreateRequest(t *testing.T) {
@@ -123,6 +149,83 @@ func TestRespondToAlreadyRespondedRequest(t *testing.T) {
 	}
 }
 
+func TestRespondWithAttachments(t *testing.T) {
+	setupTestDB(t)
+	oldDir := config.DataDir
+	config.DataDir = t.TempDir()
+	defer func() { config.DataDir = oldDir }()
+	m := newTestManager()
+
+	id, ch, err := m.CreateRequest("test-ide", "app", "what failed?")
+	if err != nil {
+		t.Fatalf("CreateRequest failed: %v", err)
+	}
+	log := db.Attachment{Name: "build.log", MIMEType: "text/x-log", Data: []byte("error: boom\n")}
+	if err := m.RespondWithAttachments(id, "see log", []db.Attachment{log}); err != nil {
+		t.Fatalf("RespondWithAttachments failed: %v", err)
+	}
+	if got := <-ch; got != "see log" {
+		t.Errorf("expected 'see log', got %q", got)
+	}
+
+	var stored []db.Attachment
+	db.Get().Where("request_id = ?", id).Find(&stored)
+	if len(stored) != 1 || !stored[0].FromHuman || stored[0].Name != "build.log" {
+		t.Fatalf("unexpected attachments: %+v", stored)
+	}
+
+	// A refused answer leaves none of its files behind.
+	if err := m.RespondWithAttachments(id, "again", []db.Attachment{log}); err == nil {
+		t.Fatal("expected responding twice to fail")
+	}
+	var count int64
+	db.Get().Model(&db.Attachment{}).Where("request_id = ?", id).Count(&count)
+	entries, _ := os.ReadDir(filepath.Join(config.AttachmentsDir(), fmt.Sprint(id)))
+	if count != 1 || len(entries) != 1 {
+		t.Errorf("expected only the first answer's file, got %d rows and %d files", count, len(entries))
+	}
+}
+
+func TestConcurrentRespondWithAttachments(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+
+	id, _, err := m.CreateRequest("test-ide", "app", "what failed?")
+	if err != nil {
+		t.Fatalf("CreateRequest failed: %v", err)
+	}
+
+	// The web UI and the CLI answer at once; one answer wins and the
+	// other leaves nothing behind, in particular not the winner's files.
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			log := db.Attachment{Name: fmt.Sprintf("answer-%d.log", i), MIMEType: "text/x-log", Data: []byte("error: boom\n")}
+			errs[i] = m.RespondWithAttachments(id, fmt.Sprintf("answer %d", i), []db.Attachment{log})
+		}()
+	}
+	wg.Wait()
+	if (errs[0] == nil) == (errs[1] == nil) {
+		t.Fatalf("expected exactly one answer to win, got %v and %v", errs[0], errs[1])
+	}
+
+	var stored []db.Attachment
+	db.Get().Where("request_id = ?", id).Find(&stored)
+	if len(stored) != 1 || stored[0].Index != 0 {
+		t.Fatalf("expected the winner's attachment only, got %+v", stored)
+	}
+	if _, err := os.Stat(filepath.Join(config.AttachmentsDir(), filepath.FromSlash(stored[0].Path))); err != nil {
+		t.Errorf("expected the winner's file to remain: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(config.AttachmentsDir(), fmt.Sprint(id)))
+	if len(entries) != 1 {
+		t.Errorf("expected a single file, got %d", len(entries))
+	}
+}
+
 func TestRespondToNonExistentRequest(t *testing.T) {
 	setupTestDB(t)
 	m := newTestManager()
@@ -182,3 +285,584 @@ func TestConcurrentRespond(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestRespondToChoiceRequest(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+
+	req := db.Request{
+		SourceName: "test-ide",
+		AppName:    "app",
+		Kind:       "choice",
+		Question:   "Which database?",
+		Options:    []db.Option{{Label: "sqlite"}, {Label: "postgres", Description: "shared server"}},
+	}
+	ch, err := m.Create(&req)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if err := m.RespondToRequest(req.ID, "mysql"); err == nil {
+		t.Fatal("expected error for a response that is not an option")
+	}
+	if err := m.RespondToRequest(req.ID, "postgres"); err != nil {
+		t.Fatalf("RespondToRequest failed: %v", err)
+	}
+	if resp := <-ch; resp != "postgres" {
+		t.Errorf("expected 'postgres', got %q", resp)
+	}
+
+	var stored db.Request
+	db.Get().First(&stored, req.ID)
+	if len(stored.Options) != 2 || stored.Options[1].Description != "shared server" {
+		t.Errorf("expected options to round-trip, got %+v", stored.Options)
+	}
+}
+
+func TestRespondToChoiceRequestAllowOther(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+
+	req := db.Request{
+		SourceName: "test-ide",
+		AppName:    "app",
+		Kind:       "choice",
+		Question:   "Which database?",
+		Options:    []db.Option{{Label: "sqlite"}},
+		AllowOther: true,
+	}
+	if _, err := m.Create(&req); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := m.RespondToRequest(req.ID, "duckdb"); err != nil {
+		t.Errorf("expected free-text answer to be accepted, got %v", err)
+	}
+}
+
+func TestRequestTimesOutWithDefault(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+
+	expiresAt := time.Now().Add(50 * time.Millisecond)
+	req := db.Request{
+		SourceName:      "test-ide",
+		AppName:         "app",
+		Question:        "Proceed?",
+		ExpiresAt:       &expiresAt,
+		DefaultResponse: "yes",
+	}
+	ch, err := m.Create(&req)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp != "yes" {
+			t.Errorf("expected default response 'yes', got %q", resp)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for default response")
+	}
+
+	var stored db.Request
+	db.Get().First(&stored, req.ID)
+	if stored.Status != "timed_out" {
+		t.Errorf("expected status 'timed_out', got %q", stored.Status)
+	}
+	if err := m.RespondToRequest(req.ID, "too late"); err == nil {
+		t.Error("expected error when responding to a timed-out request")
+	}
+}
+
+func TestRequestTimesOutWithoutDefault(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+
+	expiresAt := time.Now().Add(50 * time.Millisecond)
+	req := db.Request{SourceName: "test-ide", AppName: "app", Question: "Proceed?", ExpiresAt: &expiresAt}
+	ch, err := m.Create(&req)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if ok {
+			t.Errorf("expected channel to close without a value, got %q", resp)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for channel to close")
+	}
+}
+
+func TestClosingRequestStopsItsTimer(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+
+	expiresAt := time.Now().Add(time.Hour)
+	for _, close := range []func(id uint) error{
+		func(id uint) error { return m.RespondToRequest(id, "yes") },
+		m.CancelRequest,
+	} {
+		req := db.Request{SourceName: "test-ide", AppName: "app", Question: "Proceed?", ExpiresAt: &expiresAt}
+		if _, err := m.Create(&req); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		m.mu.Lock()
+		_, armed := m.timers[req.ID]
+		m.mu.Unlock()
+		if !armed {
+			t.Fatal("expected a timer for a request with a deadline")
+		}
+		if err := close(req.ID); err != nil {
+			t.Fatalf("closing the request failed: %v", err)
+		}
+		m.mu.Lock()
+		_, armed = m.timers[req.ID]
+		m.mu.Unlock()
+		if armed {
+			t.Error("expected the timer to be stopped and dropped")
+		}
+	}
+}
+
+func TestCancelRequest(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+
+	id, ch, err := m.CreateRequest("test-ide", "app", "question")
+	if err != nil {
+		t.Fatalf("CreateRequest failed: %v", err)
+	}
+	if err := m.CancelRequest(id); err != nil {
+		t.Fatalf("CancelRequest failed: %v", err)
+	}
+
+	if _, ok := <-ch; ok {
+		t.Error("expected channel to be closed")
+	}
+	m.mu.Lock()
+	_, tracked := m.channels[id]
+	m.mu.Unlock()
+	if tracked {
+		t.Error("expected channel to be removed from manager")
+	}
+
+	var req db.Request
+	db.Get().First(&req, id)
+	if req.Status != "cancelled" {
+		t.Errorf("expected status 'cancelled', got %q", req.Status)
+	}
+
+	err = m.RespondToRequest(id, "late answer")
+	var closed *ClosedError
+	if !errors.As(err, &closed) {
+		t.Fatalf("expected ClosedError, got %v", err)
+	}
+	if err := m.CancelRequest(id); err == nil {
+		t.Error("expected error when cancelling twice")
+	}
+}
+
+func TestDoneClosesOnResponse(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+
+	id, _, err := m.CreateRequest("test-ide", "app", "question")
+	if err != nil {
+		t.Fatalf("CreateRequest failed: %v", err)
+	}
+	first, second := m.Done(id), m.Done(id)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		m.RespondToRequest(id, "answer")
+	}()
+
+	for i, done := range []<-chan struct{}{first, second} {
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("waiter %d was not released", i)
+		}
+	}
+}
+
+func TestAttachExistingRequest(t *testing.T) {
+	setupTestDB(t)
+	previous := newTestManager()
+	m := newTestManager()
+
+	// A request created through a primary that has since exited.
+	id, _, err := previous.CreateRequest("test-ide", "app", "question")
+	if err != nil {
+		t.Fatalf("CreateRequest failed: %v", err)
+	}
+
+	ch, err := m.Attach(id)
+	if err != nil {
+		t.Fatalf("Attach failed: %v", err)
+	}
+	if err := m.RespondToRequest(id, "answer"); err != nil {
+		t.Fatalf("RespondToRequest failed: %v", err)
+	}
+	select {
+	case resp := <-ch:
+		if resp != "answer" {
+			t.Errorf("expected 'answer', got %q", resp)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for response on attached channel")
+	}
+
+	// Attaching to an answered request replays the answer.
+	ch, err = m.Attach(id)
+	if err != nil {
+		t.Fatalf("Attach failed: %v", err)
+	}
+	if resp := <-ch; resp != "answer" {
+		t.Errorf("expected replayed 'answer', got %q", resp)
+	}
+}
+
+func TestRecoverPendingOrphansDeadOwners(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+
+	dead := db.Request{SourceName: "test-ide", AppName: "app", Question: "lost", Status: "pending", OwnerPID: 0}
+	alive := db.Request{SourceName: "test-ide", AppName: "app", Question: "waiting", Status: "pending", OwnerPID: os.Getpid()}
+	// Pid 0 is never alive here, but this request waits on another host.
+	foreign := db.Request{SourceName: "test-ide", AppName: "app", Question: "elsewhere", Status: "pending", OwnerPID: 0, OwnerHost: "another-host"}
+	db.Get().Create(&dead)
+	db.Get().Create(&alive)
+	db.Get().Create(&foreign)
+	watched := m.Done(dead.ID)
+
+	if err := m.RecoverPending(); err != nil {
+		t.Fatalf("RecoverPending failed: %v", err)
+	}
+	select {
+	case <-watched:
+	default:
+		t.Error("expected watchers of the orphaned request to be woken")
+	}
+	m.mu.Lock()
+	_, kept := m.done[dead.ID]
+	m.mu.Unlock()
+	if kept {
+		t.Error("expected the orphaned request to be forgotten")
+	}
+
+	db.Get().First(&dead, dead.ID)
+	db.Get().First(&alive, alive.ID)
+	if dead.Status != "orphaned" {
+		t.Errorf("expected dead owner's request to be orphaned, got %q", dead.Status)
+	}
+	if alive.Status != "pending" {
+		t.Errorf("expected live owner's request to stay pending, got %q", alive.Status)
+	}
+	db.Get().First(&foreign, foreign.ID)
+	if foreign.Status != "pending" {
+		t.Errorf("expected another host's request to stay pending, got %q", foreign.Status)
+	}
+
+	var closed *ClosedError
+	if err := m.RespondToRequest(dead.ID, "answer"); !errors.As(err, &closed) {
+		t.Errorf("expected ClosedError for orphaned request, got %v", err)
+	}
+}
+
+func TestSyncDeliversAnswersGivenElsewhere(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+	// Another host's primary, sharing the database.
+	other := newTestManager()
+
+	answered, answer, err := m.CreateRequest("test-ide", "app", "Deploy?")
+	if err != nil {
+		t.Fatalf("CreateRequest failed: %v", err)
+	}
+	deleted, gone, err := m.CreateRequest("test-ide", "app", "Migrate?")
+	if err != nil {
+		t.Fatalf("CreateRequest failed: %v", err)
+	}
+	waiting, _, err := m.CreateRequest("test-ide", "app", "Rollback?")
+	if err != nil {
+		t.Fatalf("CreateRequest failed: %v", err)
+	}
+
+	if err := other.RespondToRequest(answered, "yes"); err != nil {
+		t.Fatalf("RespondToRequest failed: %v", err)
+	}
+	db.Get().Unscoped().Delete(&db.Request{}, deleted)
+	if err := m.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	select {
+	case got := <-answer:
+		if got != "yes" {
+			t.Errorf("expected the answer given elsewhere, got %q", got)
+		}
+	default:
+		t.Error("expected the answer to be delivered")
+	}
+	if _, open := <-gone; open {
+		t.Error("expected the deleted request's channel to be closed")
+	}
+	if ids := m.watched(); len(ids) != 1 || ids[0] != waiting {
+		t.Errorf("expected only the pending request to stay watched, got %v", ids)
+	}
+}
+
+func TestCreateReclaimsOrphanedRequest(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+
+	orphan := db.Request{SourceName: "test-ide", AppName: "app", Kind: "question", Question: "Deploy now?", Status: "orphaned"}
+	db.Get().Create(&orphan)
+
+	req := db.Request{SourceName: "test-ide", AppName: "app", Question: "Deploy now?", OwnerPID: os.Getpid()}
+	ch, err := m.Create(&req)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if req.ID != orphan.ID {
+		t.Fatalf("expected orphaned request %d to be reclaimed, got new request %d", orphan.ID, req.ID)
+	}
+	if req.Status != "pending" || req.OwnerPID != os.Getpid() {
+		t.Errorf("expected reclaimed request to be pending and owned by us, got %q/%d", req.Status, req.OwnerPID)
+	}
+
+	if err := m.RespondToRequest(req.ID, "yes"); err != nil {
+		t.Fatalf("RespondToRequest failed: %v", err)
+	}
+	if resp := <-ch; resp != "yes" {
+		t.Errorf("expected 'yes', got %q", resp)
+	}
+}
+
+func TestCreateLinksFollowUpsIntoThread(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+
+	first := db.Request{SourceName: "ide", AppName: "app", Question: "Use Postgres?"}
+	if _, err := m.Create(&first); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if first.ThreadID != first.ID {
+		t.Fatalf("expected a new thread %d, got %d", first.ID, first.ThreadID)
+	}
+
+	// Following up on the thread attaches to its latest request.
+	second := db.Request{SourceName: "ide", AppName: "app", Question: "Then also add pgbouncer?", ThreadID: first.ThreadID}
+	if _, err := m.Create(&second); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if second.ThreadID != first.ID || second.ParentID == nil || *second.ParentID != first.ID {
+		t.Fatalf("expected follow-up to #%d in thread %d, got parent %v thread %d", first.ID, first.ID, second.ParentID, second.ThreadID)
+	}
+
+	// Following up on a request puts the new one in that request's thread.
+	third := db.Request{SourceName: "ide", AppName: "app", Question: "And read replicas?", ParentID: &second.ID}
+	if _, err := m.Create(&third); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if third.ThreadID != first.ID {
+		t.Errorf("expected thread %d, got %d", first.ID, third.ThreadID)
+	}
+
+	var stored db.Request
+	if err := db.Get().First(&stored, third.ID).Error; err != nil {
+		t.Fatalf("failed to fetch from DB: %v", err)
+	}
+	if stored.ThreadID != first.ID || stored.ParentID == nil || *stored.ParentID != second.ID {
+		t.Errorf("thread not stored: thread %d parent %v", stored.ThreadID, stored.ParentID)
+	}
+}
+
+func TestCreateRejectsUnknownThread(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+
+	missing := uint(1 << 30)
+	if _, err := m.Create(&db.Request{SourceName: "ide", AppName: "app", Question: "q", ThreadID: missing}); !errors.Is(err, ErrUnknownThread) {
+		t.Errorf("expected ErrUnknownThread for an unknown thread, got %v", err)
+	}
+	if _, err := m.Create(&db.Request{SourceName: "ide", AppName: "app", Question: "q", ParentID: &missing}); !errors.Is(err, ErrUnknownThread) {
+		t.Errorf("expected ErrUnknownThread for an unknown parent, got %v", err)
+	}
+
+	first := db.Request{SourceName: "ide", AppName: "app", Question: "q"}
+	if _, err := m.Create(&first); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	other := db.Request{SourceName: "ide", AppName: "app", Question: "other"}
+	if _, err := m.Create(&other); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := m.Create(&db.Request{SourceName: "ide", AppName: "app", Question: "q2", ThreadID: other.ThreadID, ParentID: &first.ID}); !errors.Is(err, ErrUnknownThread) {
+		t.Errorf("expected ErrUnknownThread for a parent outside the thread, got %v", err)
+	}
+}
+
+func TestTakeMessagesDeliversOnce(t *testing.T) {
+	setupTestDB(t)
+	// Read times are stored in UTC wherever the process runs.
+	oldLocal := time.Local
+	time.Local = time.FixedZone("EST", -5*60*60)
+	t.Cleanup(func() { time.Local = oldLocal })
+
+	if _, err := PostMessage("msg-ide", "app", "stop, wrong direction"); err != nil {
+		t.Fatalf("PostMessage failed: %v", err)
+	}
+	if _, err := PostMessage("msg-ide", "", "for every app"); err != nil {
+		t.Fatalf("PostMessage failed: %v", err)
+	}
+	if _, err := PostMessage("msg-ide", "other-app", "not for app"); err != nil {
+		t.Fatalf("PostMessage failed: %v", err)
+	}
+
+	got, err := TakeMessages("msg-ide", "app")
+	if err != nil {
+		t.Fatalf("TakeMessages failed: %v", err)
+	}
+	if len(got) != 2 || got[0].Body != "stop, wrong direction" || got[1].Body != "for every app" {
+		t.Fatalf("expected the app's message and the source-wide one, got %+v", got)
+	}
+	if got[0].ReadAt == nil {
+		t.Error("expected taken messages to be marked read")
+	}
+	var readAt string
+	db.Get().Raw("SELECT CAST(read_at AS TEXT) FROM messages WHERE id = ?", got[0].ID).Scan(&readAt)
+	if !strings.HasSuffix(readAt, "+00:00") {
+		t.Errorf("expected the read time in UTC, got %q", readAt)
+	}
+
+	again, err := TakeMessages("msg-ide", "app")
+	if err != nil {
+		t.Fatalf("TakeMessages failed: %v", err)
+	}
+	if len(again) != 0 {
+		t.Errorf("expected messages to be delivered once, got %+v", again)
+	}
+
+	other, _ := TakeMessages("msg-ide", "other-app")
+	if len(other) != 1 || other[0].Body != "not for app" {
+		t.Errorf("expected other-app's own message, got %+v", other)
+	}
+}
+
+func TestNotifyAndAcknowledge(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+
+	events := Broker.Subscribe()
+	defer Broker.Unsubscribe(events)
+
+	req := db.Request{SourceName: "ide", AppName: "app", Question: "Deployed to staging", OwnerPID: 999999}
+	if err := m.Notify(&req); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if req.Kind != "notification" || req.Status != "unread" || req.ThreadID != req.ID {
+		t.Fatalf("unexpected notification: kind %q status %q thread %d", req.Kind, req.Status, req.ThreadID)
+	}
+	select {
+	case msg := <-events:
+		if EventName(msg) != "new-notification" {
+			t.Errorf("expected new-notification event, got %s", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an SSE event")
+	}
+
+	if err := m.RespondToRequest(req.ID, "ok"); err == nil {
+		t.Error("expected replying to a notification to fail")
+	}
+	// Its owner is long gone, but nobody waits on a notification.
+	if err := m.RecoverPending(); err != nil {
+		t.Fatalf("RecoverPending failed: %v", err)
+	}
+
+	if err := m.Acknowledge(req.ID); err != nil {
+		t.Fatalf("Acknowledge failed: %v", err)
+	}
+	var stored db.Request
+	db.Get().First(&stored, req.ID)
+	if stored.Status != "acknowledged" || stored.RespondedAt == nil {
+		t.Errorf("expected acknowledged notification, got status %q", stored.Status)
+	}
+	if err := m.Acknowledge(req.ID); err == nil {
+		t.Error("expected a second acknowledgement to fail")
+	}
+
+	question := db.Request{SourceName: "ide", AppName: "app", Question: "Proceed?"}
+	if _, err := m.Create(&question); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := m.Acknowledge(question.ID); err == nil {
+		t.Error("expected acknowledging a question to fail")
+	}
+	if err := m.Acknowledge(999); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown notification, got %v", err)
+	}
+}
+
+func TestManagerOnMemoryStore(t *testing.T) {
+	s := setupMemoryStore(t)
+	m := newTestManager()
+
+	first := db.Request{SourceName: "ide", AppName: "app", Question: "Use Postgres?", OwnerPID: os.Getpid()}
+	ch, err := m.Create(&first)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := m.RespondToRequest(first.ID, "yes"); err != nil {
+		t.Fatalf("RespondToRequest failed: %v", err)
+	}
+	if resp := <-ch; resp != "yes" {
+		t.Errorf("expected 'yes', got %q", resp)
+	}
+
+	followUp := db.Request{SourceName: "ide", AppName: "app", Question: "Which version?", ThreadID: first.ThreadID}
+	if _, err := m.Create(&followUp); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if followUp.ParentID == nil || *followUp.ParentID != first.ID {
+		t.Errorf("expected follow-up to %d, got %v", first.ID, followUp.ParentID)
+	}
+	if err := m.CancelRequest(followUp.ID); err != nil {
+		t.Fatalf("CancelRequest failed: %v", err)
+	}
+	var closed *ClosedError
+	if err := m.RespondToRequest(followUp.ID, "16"); !errors.As(err, &closed) || closed.Status != "cancelled" {
+		t.Errorf("expected ClosedError for a cancelled request, got %v", err)
+	}
+
+	lost := db.Request{SourceName: "ide", AppName: "app", Question: "Deploy?", Status: "pending"}
+	if err := s.Create(&lost); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := m.RecoverPending(); err != nil {
+		t.Fatalf("RecoverPending failed: %v", err)
+	}
+	again := db.Request{SourceName: "ide", AppName: "app", Question: "Deploy?", OwnerPID: os.Getpid()}
+	if _, err := m.Create(&again); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if again.ID != lost.ID || again.Status != "pending" {
+		t.Errorf("expected orphaned request %d reclaimed, got %d (%s)", lost.ID, again.ID, again.Status)
+	}
+
+	n, err := m.ClearHistory("ide", "")
+	if err != nil {
+		t.Fatalf("ClearHistory failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 closed requests deleted, got %d", n)
+	}
+	if _, err := s.Get(again.ID); err != nil {
+		t.Errorf("expected the pending request kept, got %v", err)
+	}
+}
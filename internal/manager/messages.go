@@ -0,0 +1,62 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"gorm.io/gorm"
+)
+
+// PostMessage stores a message from the human for the agents of
+// sourceName working on appName, or on any app if appName is empty.
+func PostMessage(sourceName, appName, body string) (*db.Message, error) {
+	database := db.Get()
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	msg := db.Message{SourceName: sourceName, AppName: appName, Body: body}
+	if err := database.Create(&msg).Error; err != nil {
+		return nil, fmt.Errorf("failed to store message: %w", err)
+	}
+	return &msg, nil
+}
+
+// TakeMessages returns the unread messages for an agent of sourceName
+// working on appName, oldest first, and marks them read. Each message is
+// handed to exactly one caller, even when several agents check at once.
+func TakeMessages(sourceName, appName string) ([]db.Message, error) {
+	database := db.Get()
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var taken []db.Message
+	err := database.Transaction(func(tx *gorm.DB) error {
+		var unread []db.Message
+		err := tx.Where("source_name = ? AND (app_name = ? OR app_name = '') AND read_at IS NULL", sourceName, appName).
+			Order("id ASC").Find(&unread).Error
+		if err != nil {
+			return fmt.Errorf("failed to load messages: %w", err)
+		}
+
+		now := time.Now().UTC()
+		for _, msg := range unread {
+			result := tx.Model(&db.Message{}).Where("id = ? AND read_at IS NULL", msg.ID).Update("read_at", &now)
+			if result.Error != nil {
+				return fmt.Errorf("failed to mark message %d read: %w", msg.ID, result.Error)
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			msg.ReadAt = &now
+			taken = append(taken, msg)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return taken, nil
+}
@@ -0,0 +1,67 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/attachment"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/process"
+	"github.com/tejzpr/rishvan-mcp/internal/store"
+)
+
+// Notifications are informational entries an agent records without waiting
+// for anyone. They start out "unread" and become "acknowledged" once the
+// human has seen them; they never enter the pending state, so they cannot
+// time out, be cancelled or be orphaned.
+
+// Notify stores req as an unread notification and announces it to the UI.
+func (m *RequestManager) Notify(req *db.Request) error {
+	requests, err := requestStore()
+	if err != nil {
+		return err
+	}
+
+	if err := attachment.Validate(req.Attachments); err != nil {
+		return err
+	}
+	req.Kind = "notification"
+	req.Status = "unread"
+	req.ExpiresAt = nil
+	if req.OwnerHost, err = process.HostID(); err != nil {
+		return err
+	}
+	if err := insert(requests, req); err != nil {
+		return err
+	}
+
+	Broker.PublishNotification(req.ID, req.SourceName, req.AppName, req.Question)
+	return nil
+}
+
+// Acknowledge marks an unread notification as seen by the human.
+func (m *RequestManager) Acknowledge(id uint) error {
+	requests, err := requestStore()
+	if err != nil {
+		return err
+	}
+
+	req, err := requests.Get(id)
+	if err != nil {
+		return err
+	}
+	if req.Kind != "notification" {
+		return fmt.Errorf("request %d is not a notification", id)
+	}
+
+	if err := requests.Acknowledge(id, time.Now()); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return fmt.Errorf("notification %d was already acknowledged", id)
+		}
+		return err
+	}
+
+	Broker.PublishEvent("notification-acknowledged", id)
+	return nil
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/process"
+	"github.com/tejzpr/rishvan-mcp/internal/store"
+)
+
+// RecoverPending is run when this process becomes the primary. Pending
+// requests whose owning process has exited can never be delivered and are
+// marked orphaned; the timeouts of the remaining ones are re-armed, since
+// the timers of the previous primary died with it. Only requests of this
+// host are touched: the pids of other hosts sharing a PostgreSQL database
+// mean nothing here, and their own primaries time their requests out.
+func (m *RequestManager) RecoverPending() error {
+	requests, err := requestStore()
+	if err != nil {
+		return err
+	}
+	host, err := process.HostID()
+	if err != nil {
+		return err
+	}
+
+	pending, err := requests.List(store.Query{Statuses: []string{"pending"}})
+	if err != nil {
+		return fmt.Errorf("failed to load pending requests: %w", err)
+	}
+
+	for _, req := range pending {
+		// Requests stored before hosts were recorded come from a time
+		// when a database had a single host.
+		if req.OwnerHost != "" && req.OwnerHost != host {
+			continue
+		}
+		if !process.Alive(req.OwnerPID) {
+			err := requests.Orphan(req.ID)
+			if errors.Is(err, store.ErrConflict) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to orphan request %d: %w", req.ID, err)
+			}
+			log.Printf("rishvan-mcp: request %d orphaned (owner process %d is gone)", req.ID, req.OwnerPID)
+			m.resolve(req.ID, "")
+			Broker.PublishEvent("request-orphaned", req.ID)
+			continue
+		}
+		m.arm(&req)
+	}
+	return nil
+}
+
+// reclaim looks for an orphaned request asking exactly what req asks, from
+// the same source and app, and hands it back to req's owner. On success req
+// is replaced by the reclaimed row.
+func (m *RequestManager) reclaim(requests store.RequestStore, req *db.Request) (bool, error) {
+	orphans, err := requests.IDs(store.Query{
+		SourceNames: []string{req.SourceName},
+		AppNames:    []string{req.AppName},
+		Kind:        req.Kind,
+		Question:    req.Question,
+		Statuses:    []string{"orphaned"},
+		Limit:       1,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to look up orphaned requests: %w", err)
+	}
+	if len(orphans) == 0 {
+		return false, nil
+	}
+
+	id := orphans[0]
+	err = requests.Reclaim(id, req.OwnerPID, req.OwnerHost, req.ExpiresAt)
+	if errors.Is(err, store.ErrConflict) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to reclaim request %d: %w", id, err)
+	}
+
+	reclaimed, err := requests.Get(id)
+	if err != nil {
+		return false, fmt.Errorf("failed to reload request %d: %w", id, err)
+	}
+	*req = *reclaimed
+	return true, nil
+}
@@ -0,0 +1,256 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/attachment"
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/export"
+	"github.com/tejzpr/rishvan-mcp/internal/store"
+)
+
+// janitorInterval is how often the primary applies the retention policy.
+const janitorInterval = time.Hour
+
+// deleteBatch is how many requests are deleted per statement.
+const deleteBatch = 500
+
+// RetentionPolicy decides which closed requests are deleted. Pending
+// requests are never deleted.
+type RetentionPolicy struct {
+	// MaxAge deletes requests created longer ago than this. Zero disables
+	// it.
+	MaxAge time.Duration
+	// MaxPerSource keeps only the newest requests of each source. Zero
+	// disables it.
+	MaxPerSource int
+	// Keep lists statuses that are never deleted.
+	Keep []string
+	// Archive appends requests to a JSON Lines file in
+	// config.ArchiveDir before deleting them.
+	Archive bool
+}
+
+// RetentionFromConfig returns the policy set by the --retain-* flags.
+func RetentionFromConfig() RetentionPolicy {
+	return RetentionPolicy{
+		MaxAge:       config.RetainFor,
+		MaxPerSource: config.RetainPerSource,
+		Keep:         config.RetainKeep,
+		Archive:      config.RetainArchive,
+	}
+}
+
+// PurgeResult reports what a retention run did.
+type PurgeResult struct {
+	Deleted int
+	// ArchiveFile is where the deleted requests were archived, if they
+	// were.
+	ArchiveFile string
+}
+
+// RunJanitor applies p now and then every hour. It never returns; the
+// primary runs it in its own goroutine.
+func (m *RequestManager) RunJanitor(p RetentionPolicy) {
+	for {
+		res, err := m.Purge(p, time.Now())
+		switch {
+		case err != nil:
+			log.Printf("rishvan-mcp: retention failed: %v", err)
+		case res.ArchiveFile != "":
+			log.Printf("rishvan-mcp: retention deleted %d request(s), archived to %s", res.Deleted, res.ArchiveFile)
+		case res.Deleted > 0:
+			log.Printf("rishvan-mcp: retention deleted %d request(s)", res.Deleted)
+		}
+		time.Sleep(janitorInterval)
+	}
+}
+
+// Purge deletes the requests that p no longer retains as of now.
+func (m *RequestManager) Purge(p RetentionPolicy, now time.Time) (PurgeResult, error) {
+	requests, err := requestStore()
+	if err != nil {
+		return PurgeResult{}, err
+	}
+
+	ids, err := expired(requests, p, now)
+	if err != nil || len(ids) == 0 {
+		return PurgeResult{}, err
+	}
+
+	var res PurgeResult
+	if p.Archive {
+		if res.ArchiveFile, err = archive(ids, now); err != nil {
+			return PurgeResult{}, err
+		}
+	}
+	deleted, err := m.Delete(ids)
+	res.Deleted = len(deleted)
+	if len(deleted) > 0 {
+		Broker.PublishEvent("requests-deleted", 0)
+	}
+	return res, err
+}
+
+// expired lists the requests p no longer retains, oldest first.
+func expired(requests store.RequestStore, p RetentionPolicy, now time.Time) ([]uint, error) {
+	closed := store.Query{ExcludeStatuses: append([]string{"pending"}, p.Keep...)}
+
+	drop := map[uint]bool{}
+	if p.MaxAge > 0 {
+		q := closed
+		q.Until = now.Add(-p.MaxAge)
+		ids, err := requests.IDs(q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find expired requests: %w", err)
+		}
+		for _, id := range ids {
+			drop[id] = true
+		}
+	}
+	if p.MaxPerSource > 0 {
+		sources, err := requests.Sources(closed)
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range sources {
+			q := closed
+			q.SourceNames = []string{source}
+			ids, err := requests.IDs(q)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find requests of %s: %w", source, err)
+			}
+			if len(ids) > p.MaxPerSource {
+				for _, id := range ids[p.MaxPerSource:] {
+					drop[id] = true
+				}
+			}
+		}
+	}
+	if len(drop) == 0 {
+		return nil, nil
+	}
+
+	// Sort by id, which is creation order, so archives read oldest first.
+	ids := make([]uint, 0, len(drop))
+	for id := range drop {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// archive appends the requests ids to this month's archive file and
+// returns its path.
+func archive(ids []uint, now time.Time) (string, error) {
+	database := db.Get()
+	if database == nil {
+		return "", fmt.Errorf("archiving needs the database, which is not initialized")
+	}
+	if err := os.MkdirAll(config.ArchiveDir(), 0700); err != nil {
+		return "", fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	path := filepath.Join(config.ArchiveDir(), "requests-"+now.Format("2006-01")+".jsonl")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to open archive: %w", err)
+	}
+	for start := 0; start < len(ids); start += deleteBatch {
+		batch := ids[start:min(start+deleteBatch, len(ids))]
+		if err := export.Write(database, f, export.JSONL, export.Filter{IDs: batch}); err != nil {
+			f.Close()
+			return "", fmt.Errorf("failed to archive requests: %w", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to archive requests: %w", err)
+	}
+	return path, nil
+}
+
+// Delete permanently removes the requests ids, attachments included, and
+// returns the ids it removed. Pending requests are skipped.
+func (m *RequestManager) Delete(ids []uint) ([]uint, error) {
+	requests, err := requestStore()
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []uint
+	for start := 0; start < len(ids); start += deleteBatch {
+		batch, err := requests.Purge(ids[start:min(start+deleteBatch, len(ids))])
+		for _, id := range batch {
+			if err := attachment.RemoveFiles(id); err != nil {
+				log.Printf("rishvan-mcp: %v", err)
+			}
+			// Wake anyone still watching it; it is gone for good.
+			m.resolve(id, "")
+		}
+		deleted = append(deleted, batch...)
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// DeleteRequest permanently removes one request. A pending request is
+// refused with ErrPending.
+func (m *RequestManager) DeleteRequest(id uint) error {
+	requests, err := requestStore()
+	if err != nil {
+		return err
+	}
+
+	req, err := requests.Get(id)
+	if err != nil {
+		return err
+	}
+	if req.Status == "pending" {
+		return ErrPending
+	}
+	deleted, err := m.Delete([]uint{id})
+	if err != nil {
+		return err
+	}
+	if len(deleted) == 0 {
+		// Reclaimed by its agent in the meantime.
+		return ErrPending
+	}
+	log.Printf("rishvan-mcp: deleted request %d", id)
+	Broker.PublishEvent("request-deleted", id)
+	return nil
+}
+
+// ClearHistory deletes every closed request, or those of one source or
+// app, and returns how many it deleted.
+func (m *RequestManager) ClearHistory(sourceName, appName string) (int, error) {
+	requests, err := requestStore()
+	if err != nil {
+		return 0, err
+	}
+
+	q := store.Query{ExcludeStatuses: []string{"pending"}, Oldest: true}
+	if sourceName != "" {
+		q.SourceNames = []string{sourceName}
+	}
+	if appName != "" {
+		q.AppNames = []string{appName}
+	}
+	ids, err := requests.IDs(q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list history: %w", err)
+	}
+	deleted, err := m.Delete(ids)
+	if len(deleted) > 0 {
+		log.Printf("rishvan-mcp: cleared history, deleted %d request(s)", len(deleted))
+		Broker.PublishEvent("requests-deleted", 0)
+	}
+	return len(deleted), err
+}
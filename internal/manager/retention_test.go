@@ -0,0 +1,164 @@
+package manager
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+)
+
+// seedHistory stores closed requests for source, one a day, oldest
+// first, plus one pending request created before all of them.
+func seedHistory(t *testing.T, source string, now time.Time, statuses ...string) []uint {
+	t.Helper()
+	d := db.Get()
+	pending := db.Request{SourceName: source, AppName: "app", Question: "still waiting", Status: "pending"}
+	pending.CreatedAt = now.Add(-365 * 24 * time.Hour)
+	if err := d.Create(&pending).Error; err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	var ids []uint
+	for i, status := range statuses {
+		req := db.Request{SourceName: source, AppName: "app", Question: fmt.Sprintf("q%d", i), Status: status, Response: "a"}
+		req.CreatedAt = now.Add(-time.Duration(len(statuses)-i) * 24 * time.Hour)
+		if err := d.Create(&req).Error; err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		ids = append(ids, req.ID)
+	}
+	return ids
+}
+
+func remaining(t *testing.T, source string) (ids []uint, pending int) {
+	t.Helper()
+	var reqs []db.Request
+	db.Get().Unscoped().Where("source_name = ?", source).Order("id").Find(&reqs)
+	for _, r := range reqs {
+		if r.Status == "pending" {
+			pending++
+			continue
+		}
+		ids = append(ids, r.ID)
+	}
+	return ids, pending
+}
+
+func TestPurgeByAgeAndStatus(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+	now := time.Now()
+	// Created 4, 3, 2 and 1 days ago.
+	ids := seedHistory(t, "purge-age", now, "responded", "cancelled", "timed_out", "responded")
+
+	res, err := m.Purge(RetentionPolicy{MaxAge: 60 * time.Hour, Keep: []string{"cancelled"}}, now)
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if res.Deleted != 1 || res.ArchiveFile != "" {
+		t.Errorf("unexpected result %+v", res)
+	}
+	left, pending := remaining(t, "purge-age")
+	if want := ids[1:]; !slices.Equal(left, want) {
+		t.Errorf("expected %v to remain, got %v", want, left)
+	}
+	if pending != 1 {
+		t.Errorf("expected the pending request to survive, got %d", pending)
+	}
+}
+
+func TestPurgePerSourceWithArchive(t *testing.T) {
+	setupTestDB(t)
+	oldDir := config.DataDir
+	config.DataDir = t.TempDir()
+	defer func() { config.DataDir = oldDir }()
+	m := newTestManager()
+	now := time.Now()
+	ids := seedHistory(t, "purge-cap", now, "responded", "responded", "orphaned", "acknowledged", "responded")
+
+	// A file attached to a request that is about to go.
+	dir := filepath.Join(config.AttachmentsDir(), fmt.Sprint(ids[0]))
+	os.MkdirAll(dir, 0700)
+	db.Get().Create(&db.Attachment{RequestID: ids[0], Name: "x.txt", MIMEType: "text/plain", Path: "x.txt"})
+
+	res, err := m.Purge(RetentionPolicy{MaxPerSource: 2, Archive: true}, now)
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if res.Deleted < 3 {
+		t.Errorf("expected at least 3 deletions, got %+v", res)
+	}
+	left, pending := remaining(t, "purge-cap")
+	if want := ids[3:]; !slices.Equal(left, want) || pending != 1 {
+		t.Errorf("expected %v and the pending request to remain, got %v and %d pending", want, left, pending)
+	}
+
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected the attachment directory to be removed, got %v", err)
+	}
+	var count int64
+	db.Get().Model(&db.Attachment{}).Where("request_id = ?", ids[0]).Count(&count)
+	if count != 0 {
+		t.Errorf("expected the attachment row to be removed, got %d", count)
+	}
+
+	f, err := os.Open(res.ArchiveFile)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer f.Close()
+	lines := 0
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		lines++
+	}
+	if lines != res.Deleted {
+		t.Errorf("expected %d archived requests, got %d", res.Deleted, lines)
+	}
+}
+
+func TestDeleteRequest(t *testing.T) {
+	setupTestDB(t)
+	m := newTestManager()
+
+	id, _, err := m.CreateRequest("test-ide", "app", "keep me?")
+	if err != nil {
+		t.Fatalf("CreateRequest failed: %v", err)
+	}
+	if err := m.DeleteRequest(id); !errors.Is(err, ErrPending) {
+		t.Fatalf("expected ErrPending, got %v", err)
+	}
+
+	if err := m.CancelRequest(id); err != nil {
+		t.Fatalf("CancelRequest failed: %v", err)
+	}
+	// A watcher that arrived after the request closed.
+	watched := m.Done(id)
+	if err := m.DeleteRequest(id); err != nil {
+		t.Fatalf("DeleteRequest failed: %v", err)
+	}
+	select {
+	case <-watched:
+	default:
+		t.Error("expected watchers of the deleted request to be woken")
+	}
+	m.mu.Lock()
+	_, kept := m.done[id]
+	m.mu.Unlock()
+	if kept {
+		t.Error("expected the deleted request to be forgotten")
+	}
+	var count int64
+	db.Get().Unscoped().Model(&db.Request{}).Where("id = ?", id).Count(&count)
+	if count != 0 {
+		t.Errorf("expected request %d to be gone, got %d rows", id, count)
+	}
+	if err := m.DeleteRequest(id); err == nil {
+		t.Error("expected deleting a missing request to fail")
+	}
+}
@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 )
@@ -31,6 +32,37 @@ func (b *SSEBroker) Unsubscribe(ch chan string) {
 
 func (b *SSEBroker) Publish(requestID uint, sourceName, appName, question string) {
 	msg := fmt.Sprintf(`{"id":%d,"source_name":%q,"app_name":%q,"question":%q}`, requestID, sourceName, appName, question)
+	b.broadcast(msg)
+}
+
+// PublishNotification announces a new notification, which clients receive
+// as a "new-notification" event shaped like a new request.
+func (b *SSEBroker) PublishNotification(requestID uint, sourceName, appName, message string) {
+	msg := fmt.Sprintf(`{"event":"new-notification","id":%d,"source_name":%q,"app_name":%q,"question":%q}`, requestID, sourceName, appName, message)
+	b.broadcast(msg)
+}
+
+// PublishEvent notifies clients that something other than a new request
+// happened to requestID. The event name travels in the message's "event"
+// field; see EventName.
+func (b *SSEBroker) PublishEvent(event string, requestID uint) {
+	msg := fmt.Sprintf(`{"event":%q,"id":%d}`, event, requestID)
+	b.broadcast(msg)
+}
+
+// EventName returns the SSE event name for a broker message. Messages
+// without an "event" field announce new requests.
+func EventName(msg string) string {
+	var meta struct {
+		Event string `json:"event"`
+	}
+	if err := json.Unmarshal([]byte(msg), &meta); err != nil || meta.Event == "" {
+		return "new-request"
+	}
+	return meta.Event
+}
+
+func (b *SSEBroker) broadcast(msg string) {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 	for ch := range b.clients {
@@ -133,3 +133,22 @@ done:
 		t.Errorf("expected 16 buffered messages, got %d", count)
 	}
 }
+
+func TestSSEBrokerPublishEvent(t *testing.T) {
+	b := &SSEBroker{
+		clients: make(map[chan string]struct{}),
+	}
+
+	ch := b.Subscribe()
+	defer b.Unsubscribe(ch)
+
+	b.PublishEvent("request-timed-out", 7)
+	b.Publish(8, "test-ide", "app", "hello")
+
+	if name := EventName(<-ch); name != "request-timed-out" {
+		t.Errorf("expected event 'request-timed-out', got %q", name)
+	}
+	if name := EventName(<-ch); name != "new-request" {
+		t.Errorf("expected event 'new-request', got %q", name)
+	}
+}
@@ -0,0 +1,96 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/store"
+)
+
+// syncInterval is how often RunSync looks for requests closed elsewhere.
+const syncInterval = 2 * time.Second
+
+// closedEvents names the event announcing each status a request closes in.
+var closedEvents = map[string]string{
+	"responded": "request-responded",
+	"timed_out": "request-timed-out",
+	"cancelled": "request-cancelled",
+	"orphaned":  "request-orphaned",
+}
+
+// RunSync calls Sync every syncInterval. It never returns; the primary
+// runs it in its own goroutine when the database is shared.
+func (m *RequestManager) RunSync() {
+	for {
+		if err := m.Sync(); err != nil {
+			log.Printf("rishvan-mcp: failed to sync requests: %v", err)
+		}
+		time.Sleep(syncInterval)
+	}
+}
+
+// Sync catches up with the watched requests that were closed or deleted
+// without going through this manager. Hosts sharing a PostgreSQL database
+// each run a primary, and the human may answer on any of them; the
+// primary whose agent is waiting learns of it only from the database.
+// Each such request is resolved as if it had been closed here.
+func (m *RequestManager) Sync() error {
+	ids := m.watched()
+	if len(ids) == 0 {
+		return nil
+	}
+	requests, err := requestStore()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		req, err := requests.Get(id)
+		if errors.Is(err, store.ErrNotFound) {
+			if m.resolve(id, "") {
+				Broker.PublishEvent("request-deleted", id)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load request %d: %w", id, err)
+		}
+		event, closed := closedEvents[req.Status]
+		if !closed {
+			continue
+		}
+		// Deliver what the original waiter would have received.
+		var response string
+		if req.Status == "responded" || req.Status == "timed_out" {
+			response = req.Response
+		}
+		if m.resolve(id, response) {
+			Broker.PublishEvent(event, id)
+		}
+	}
+	return nil
+}
+
+// watched lists the requests that have a waiter or a timer.
+func (m *RequestManager) watched() []uint {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	set := make(map[uint]bool)
+	for id := range m.channels {
+		set[id] = true
+	}
+	for id := range m.done {
+		set[id] = true
+	}
+	for id := range m.timers {
+		set[id] = true
+	}
+	ids := make([]uint, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,50 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/store"
+)
+
+// linkThread fills in req's thread from its ParentID or ThreadID before it
+// is stored. A parent puts req in the parent's thread; a thread alone makes
+// req follow up on the latest request in it. A request with neither starts
+// a new thread when it is stored.
+func linkThread(requests store.RequestStore, req *db.Request) error {
+	if req.ParentID != nil {
+		parent, err := requests.Get(*req.ParentID)
+		if errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("%w: request %d not found", ErrUnknownThread, *req.ParentID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up request %d: %w", *req.ParentID, err)
+		}
+		if req.ThreadID != 0 && req.ThreadID != threadOf(parent) {
+			return fmt.Errorf("%w: request %d is not in thread %d", ErrUnknownThread, parent.ID, req.ThreadID)
+		}
+		req.ThreadID = threadOf(parent)
+		return nil
+	}
+	if req.ThreadID == 0 {
+		return nil
+	}
+
+	last, err := requests.IDs(store.Query{ThreadID: req.ThreadID, Limit: 1})
+	if err != nil {
+		return fmt.Errorf("failed to look up thread %d: %w", req.ThreadID, err)
+	}
+	if len(last) == 0 {
+		return fmt.Errorf("%w: thread %d not found", ErrUnknownThread, req.ThreadID)
+	}
+	req.ParentID = &last[0]
+	return nil
+}
+
+func threadOf(req *db.Request) uint {
+	if req.ThreadID == 0 {
+		return req.ID
+	}
+	return req.ThreadID
+}
@@ -0,0 +1,41 @@
+package manager
+
+import (
+	"fmt"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/schema"
+)
+
+// validateResponse checks that response is an acceptable answer for req.
+// Choice requests only accept one of their option labels unless the agent
+// allowed a free-text "other" answer. Form requests need a JSON document
+// matching their schema; failures are returned as *schema.ValidationError.
+func validateResponse(req *db.Request, response string) error {
+	switch req.Kind {
+	case "choice":
+		if req.AllowOther || OptionIndex(req, response) >= 0 {
+			return nil
+		}
+		return fmt.Errorf("response must be one of the offered options")
+	case "form":
+		s, err := schema.Parse(req.Schema)
+		if err != nil {
+			return err
+		}
+		return s.ValidateJSON([]byte(response))
+	case "notification":
+		return fmt.Errorf("request %d is a notification: acknowledge it instead of replying", req.ID)
+	}
+	return nil
+}
+
+// OptionIndex returns the index of the option labelled label, or -1.
+func OptionIndex(req *db.Request, label string) int {
+	for i, opt := range req.Options {
+		if opt.Label == label {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,86 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+)
+
+const hostFile = "host-id"
+
+var (
+	hostMu     sync.Mutex
+	cachedDir  string
+	cachedHost string
+)
+
+// HostID identifies the machine whose pids Alive can check, so that hosts
+// sharing a PostgreSQL database leave each other's requests alone. It is
+// stored in the data directory on first use, which every process of the
+// install shares and which outlives restarts.
+func HostID() (string, error) {
+	hostMu.Lock()
+	defer hostMu.Unlock()
+	if cachedDir == config.DataDir && cachedHost != "" {
+		return cachedHost, nil
+	}
+
+	path := filepath.Join(config.DataDir, hostFile)
+	id, err := readHostID(path)
+	if errors.Is(err, os.ErrNotExist) {
+		id, err = createHostID(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	cachedDir, cachedHost = config.DataDir, id
+	return id, nil
+}
+
+func readHostID(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	id := strings.TrimSpace(string(b))
+	if id == "" {
+		return "", fmt.Errorf("host id file %s is empty", path)
+	}
+	return id, nil
+}
+
+// createHostID writes a new id to path unless another process wins the
+// race, in which case that process's id is returned.
+func createHostID(path string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("failed to create data directory: %w", err)
+	}
+	id := uuid.NewString()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".host-id-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to write host id: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(id + "\n"); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write host id: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write host id: %w", err)
+	}
+
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return readHostID(path)
+		}
+		return "", fmt.Errorf("failed to write host id: %w", err)
+	}
+	return id, nil
+}
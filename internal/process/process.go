@@ -0,0 +1,12 @@
+// Package process answers whether another local process is still running,
+// and which host "local" is.
+package process
+
+// Alive reports whether a process with the given pid exists. Non-positive
+// pids are never alive.
+func Alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	return alive(pid)
+}
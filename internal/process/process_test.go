@@ -0,0 +1,39 @@
+package process
+
+import (
+	"os"
+	"testing"
+
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+)
+
+func TestAlive(t *testing.T) {
+	if !Alive(os.Getpid()) {
+		t.Error("expected the current process to be alive")
+	}
+	if Alive(0) || Alive(-1) {
+		t.Error("expected non-positive pids to be reported as not alive")
+	}
+}
+
+func TestHostIDIsStoredPerDataDir(t *testing.T) {
+	old := config.DataDir
+	t.Cleanup(func() { config.DataDir = old })
+
+	config.DataDir = t.TempDir()
+	first, err := HostID()
+	if err != nil {
+		t.Fatalf("HostID: %v", err)
+	}
+	cachedHost = ""
+	again, err := HostID()
+	if err != nil || again != first {
+		t.Fatalf("expected the stored id %q to be read back, got %q (%v)", first, again, err)
+	}
+
+	config.DataDir = t.TempDir()
+	other, err := HostID()
+	if err != nil || other == "" || other == first {
+		t.Fatalf("expected another data directory to get its own id, got %q (%v)", other, err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package process
+
+import (
+	"errors"
+	"syscall"
+)
+
+func alive(pid int) bool {
+	// Signal 0 performs the existence and permission checks without
+	// delivering anything. EPERM means the process exists but belongs to
+	// another user.
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
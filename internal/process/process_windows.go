@@ -0,0 +1,16 @@
+//go:build windows
+
+package process
+
+import "os"
+
+func alive(pid int) bool {
+	// On Windows FindProcess opens a handle and fails if there is no such
+	// process.
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
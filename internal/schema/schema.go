@@ -0,0 +1,299 @@
+// Package schema implements the subset of JSON Schema used by form
+// requests: type, properties, required, additionalProperties, enum,
+// const, numeric and length bounds, pattern, items and item counts.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a decoded JSON Schema document.
+type Schema struct {
+	Type                 typeList           `json:"type,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+// typeList accepts both "type": "string" and "type": ["string", "null"].
+type typeList []string
+
+func (t *typeList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = typeList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+	*t = many
+	return nil
+}
+
+// ValidationError reports the first field that failed validation.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("field %s: %s", e.Field, e.Message)
+}
+
+// Parse decodes raw into a Schema and checks that it describes an object
+// with at least one property, which is what the web UI can render.
+func Parse(raw []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if len(s.Type) != 1 || s.Type[0] != "object" {
+		return nil, fmt.Errorf("invalid schema: top-level type must be \"object\"")
+	}
+	if len(s.Properties) == 0 {
+		return nil, fmt.Errorf("invalid schema: at least one property is required")
+	}
+	if err := s.compile("(root)"); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// compile checks the parts of s that could otherwise only fail during
+// validation, such as malformed patterns.
+func (s *Schema) compile(path string) error {
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return fmt.Errorf("invalid schema: %s has an invalid pattern: %w", path, err)
+		}
+	}
+	for name, prop := range s.Properties {
+		if prop == nil {
+			return fmt.Errorf("invalid schema: property %s is null", joinPath(path, name))
+		}
+		if err := prop.compile(joinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile(path + "[]")
+	}
+	return nil
+}
+
+// ValidateJSON decodes data and validates it against s.
+func (s *Schema) ValidateJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return &ValidationError{Field: "(root)", Message: "is not valid JSON"}
+	}
+	return s.Validate(v)
+}
+
+// Validate checks a decoded JSON value against s. Numbers may be float64
+// or json.Number.
+func (s *Schema) Validate(v any) error {
+	return s.validate("", v)
+}
+
+func (s *Schema) validate(path string, v any) error {
+	field := path
+	if field == "" {
+		field = "(root)"
+	}
+	fail := func(format string, args ...any) error {
+		return &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if len(s.Type) > 0 && !s.matchesType(v) {
+		return fail("must be of type %s", strings.Join(s.Type, " or "))
+	}
+	if len(s.Enum) > 0 && !containsValue(s.Enum, v) {
+		return fail("must be one of %s", formatValues(s.Enum))
+	}
+	if s.Const != nil && !equalValues(s.Const, v) {
+		return fail("must be %s", formatValues([]any{s.Const}))
+	}
+
+	switch val := v.(type) {
+	case string:
+		n := utf8.RuneCountInString(val)
+		if s.MinLength != nil && n < *s.MinLength {
+			if *s.MinLength == 1 {
+				return fail("must not be empty")
+			}
+			return fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			re, err := regexp.Compile(s.Pattern)
+			if err != nil {
+				return fail("has an invalid pattern in the schema")
+			}
+			if !re.MatchString(val) {
+				return fail("must match pattern %s", s.Pattern)
+			}
+		}
+	case json.Number, float64:
+		f, _ := toFloat(val)
+		if s.Minimum != nil && f < *s.Minimum {
+			return fail("must be >= %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			return fail("must be <= %v", *s.Maximum)
+		}
+		if s.ExclusiveMinimum != nil && f <= *s.ExclusiveMinimum {
+			return fail("must be > %v", *s.ExclusiveMinimum)
+		}
+		if s.ExclusiveMaximum != nil && f >= *s.ExclusiveMaximum {
+			return fail("must be < %v", *s.ExclusiveMaximum)
+		}
+	case []any:
+		if s.MinItems != nil && len(val) < *s.MinItems {
+			return fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(val) > *s.MaxItems {
+			return fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range val {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				return &ValidationError{Field: joinPath(path, name), Message: "is required"}
+			}
+		}
+		names := make([]string, 0, len(val))
+		for name := range val {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return &ValidationError{Field: joinPath(path, name), Message: "is not allowed"}
+				}
+				continue
+			}
+			if err := prop.validate(joinPath(path, name), val[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) matchesType(v any) bool {
+	for _, t := range s.Type {
+		switch t {
+		case "string":
+			if _, ok := v.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := toFloat(v); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := toFloat(v); ok && f == math.Trunc(f) {
+				return true
+			}
+		case "boolean":
+			if _, ok := v.(bool); ok {
+				return true
+			}
+		case "object":
+			if _, ok := v.(map[string]any); ok {
+				return true
+			}
+		case "array":
+			if _, ok := v.([]any); ok {
+				return true
+			}
+		case "null":
+			if v == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func equalValues(a, b any) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+func containsValue(values []any, v any) bool {
+	for _, candidate := range values {
+		if equalValues(candidate, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatValues(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		b, _ := json.Marshal(v)
+		parts[i] = string(b)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func joinPath(path, name string) string {
+	if path == "" || path == "(root)" {
+		return name
+	}
+	return path + "." + name
+}
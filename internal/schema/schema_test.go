@@ -0,0 +1,97 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+)
+
+const deploySchema = `{
+	"type": "object",
+	"properties": {
+		"branch": {"type": "string", "minLength": 1, "pattern": "^[a-z0-9/_-]+$"},
+		"env": {"type": "string", "enum": ["staging", "production"]},
+		"approve": {"type": "boolean"},
+		"replicas": {"type": "integer", "minimum": 1, "maximum": 10},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2}
+	},
+	"required": ["branch", "env", "approve"],
+	"additionalProperties": false
+}`
+
+func mustParse(t *testing.T, raw string) *Schema {
+	t.Helper()
+	s, err := Parse([]byte(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return s
+}
+
+func TestParseRejectsNonObjectSchema(t *testing.T) {
+	for _, raw := range []string{
+		`not json`,
+		`{"type": "string"}`,
+		`{"type": "object"}`,
+		`{"type": "object", "properties": {"a": {"type": "string", "pattern": "("}}}`,
+	} {
+		if _, err := Parse([]byte(raw)); err == nil {
+			t.Errorf("expected Parse(%s) to fail", raw)
+		}
+	}
+}
+
+func TestValidateAcceptsValidData(t *testing.T) {
+	s := mustParse(t, deploySchema)
+
+	err := s.ValidateJSON([]byte(`{"branch":"feature/x","env":"staging","approve":true,"replicas":3,"tags":["a"]}`))
+	if err != nil {
+		t.Errorf("expected valid data, got %v", err)
+	}
+}
+
+func TestValidateReportsFailingField(t *testing.T) {
+	s := mustParse(t, deploySchema)
+
+	cases := []struct {
+		data  string
+		field string
+	}{
+		{`{"env":"staging","approve":true}`, "branch"},
+		{`{"branch":"","env":"staging","approve":true}`, "branch"},
+		{`{"branch":"Main!","env":"staging","approve":true}`, "branch"},
+		{`{"branch":"main","env":"dev","approve":true}`, "env"},
+		{`{"branch":"main","env":"staging","approve":"yes"}`, "approve"},
+		{`{"branch":"main","env":"staging","approve":true,"replicas":2.5}`, "replicas"},
+		{`{"branch":"main","env":"staging","approve":true,"replicas":11}`, "replicas"},
+		{`{"branch":"main","env":"staging","approve":true,"tags":["a",1]}`, "tags[1]"},
+		{`{"branch":"main","env":"staging","approve":true,"tags":["a","b","c"]}`, "tags"},
+		{`{"branch":"main","env":"staging","approve":true,"extra":1}`, "extra"},
+		{`[1,2]`, "(root)"},
+	}
+	for _, tc := range cases {
+		err := s.ValidateJSON([]byte(tc.data))
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("%s: expected ValidationError, got %v", tc.data, err)
+			continue
+		}
+		if verr.Field != tc.field {
+			t.Errorf("%s: expected field %q, got %q (%v)", tc.data, tc.field, verr.Field, verr)
+		}
+	}
+}
+
+func TestValidateNestedObject(t *testing.T) {
+	s := mustParse(t, `{
+		"type": "object",
+		"properties": {
+			"db": {"type": "object", "properties": {"port": {"type": "integer"}}, "required": ["port"]}
+		}
+	}`)
+
+	err := s.ValidateJSON([]byte(`{"db":{}}`))
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Field != "db.port" {
+		t.Errorf("expected failure on db.port, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/export"
+)
+
+// The stress test runs stressProcesses copies of the test binary next to
+// itself, all writing to one SQLite file the way the primary, the CLI and
+// a promoted secondary may.
+const (
+	stressProcesses = 3
+	stressWorkers   = 4
+	stressRequests  = 25
+	stressEnv       = "RISHVAN_STRESS_DB"
+)
+
+func TestConcurrentWritersAcrossProcesses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress test")
+	}
+	path := filepath.Join(t.TempDir(), "stress.db")
+	d := openTestDB(t, path)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, stressProcesses+1)
+	for i := range stressProcesses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestStressHelperProcess$")
+			cmd.Env = append(os.Environ(), stressEnv+"="+path, "RISHVAN_STRESS_SOURCE="+fmt.Sprintf("process-%d", i))
+			if out, err := cmd.CombinedOutput(); err != nil {
+				errs <- fmt.Errorf("process %d: %v\n%s", i, err, out)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := stress(path, "test"); err != nil {
+			errs <- err
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	s := NewSQL(d)
+	// Every iteration asks one question and imports another.
+	want := int64(2 * (stressProcesses + 1) * stressWorkers * stressRequests)
+	if n, err := s.Count(Query{Statuses: []string{"responded"}}); err != nil || n != want {
+		t.Errorf("expected %d responded requests, got %d (%v)", want, n, err)
+	}
+	if n, _ := s.Count(Query{}); n != want {
+		t.Errorf("expected %d requests in all, got %d", want, n)
+	}
+}
+
+// TestStressHelperProcess is one of the processes started by
+// TestConcurrentWritersAcrossProcesses.
+func TestStressHelperProcess(t *testing.T) {
+	path := os.Getenv(stressEnv)
+	if path == "" {
+		return
+	}
+	if err := stress(path, os.Getenv("RISHVAN_STRESS_SOURCE")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// stress opens the database at path and has stressWorkers goroutines each
+// create, list and answer stressRequests requests from source, and import
+// as many. Import reads before it writes in one transaction, which is
+// what a deferred SQLite transaction cannot do while others write.
+func stress(path, source string) error {
+	d, err := db.Open(path)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := d.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	if err := db.Migrate(d); err != nil {
+		return err
+	}
+	s := NewSQL(d)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, stressWorkers)
+	for w := range stressWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			app := fmt.Sprintf("app-%d", w)
+			for i := range stressRequests {
+				req := &db.Request{SourceName: source, AppName: app, Question: fmt.Sprintf("question %d", i)}
+				if err := s.Create(req); err != nil {
+					errs <- fmt.Errorf("%s/%s: %w", source, app, err)
+					return
+				}
+				if _, err := s.List(Query{SourceNames: []string{source}, Limit: 10}); err != nil {
+					errs <- fmt.Errorf("%s/%s: %w", source, app, err)
+					return
+				}
+				if err := s.Respond(req.ID, "answer", time.Now()); err != nil {
+					errs <- fmt.Errorf("%s/%s: %w", source, app, err)
+					return
+				}
+				rec := export.Record{UUID: uuid.NewString(), SourceName: source, AppName: app, Question: req.Question, Status: "responded", CreatedAt: time.Now()}
+				if _, err := export.Import(d, []export.Record{rec}); err != nil {
+					errs <- fmt.Errorf("%s/%s: %w", source, app, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
@@ -0,0 +1,247 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+)
+
+// Memory is a RequestStore that keeps requests in memory, for tests that
+// need no database. It behaves like SQL but stores no attachments.
+type Memory struct {
+	mu       sync.Mutex
+	nextID   uint
+	requests map[uint]*db.Request
+}
+
+// NewMemory returns an empty Memory store.
+func NewMemory() *Memory {
+	return &Memory{requests: make(map[uint]*db.Request)}
+}
+
+func (m *Memory) Create(req *db.Request) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.nextID++
+	req.ID = m.nextID
+	if req.UUID == "" {
+		req.UUID = uuid.NewString()
+	}
+	if req.CreatedAt.IsZero() {
+		req.CreatedAt = now
+	}
+	if req.UpdatedAt.IsZero() {
+		req.UpdatedAt = now
+	}
+	if req.Kind == "" {
+		req.Kind = "question"
+	}
+	if req.Status == "" {
+		req.Status = "pending"
+	}
+	if req.ThreadID == 0 {
+		req.ThreadID = req.ID
+	}
+	stored := clone(req)
+	stored.Attachments = nil
+	m.requests[req.ID] = stored
+	return nil
+}
+
+func (m *Memory) Get(id uint) (*db.Request, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	req, ok := m.requests[id]
+	if !ok {
+		return nil, fmt.Errorf("request %d %w", id, ErrNotFound)
+	}
+	return clone(req), nil
+}
+
+func (m *Memory) List(q Query) ([]db.Request, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matched := m.match(q)
+	requests := make([]db.Request, len(matched))
+	for i, req := range matched {
+		requests[i] = *clone(req)
+	}
+	return requests, nil
+}
+
+func (m *Memory) Count(q Query) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q.After, q.Limit = nil, 0
+	return int64(len(m.match(q))), nil
+}
+
+func (m *Memory) IDs(q Query) ([]uint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []uint
+	for _, req := range m.match(q) {
+		ids = append(ids, req.ID)
+	}
+	return ids, nil
+}
+
+func (m *Memory) Sources(q Query) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var sources []string
+	for _, req := range m.match(q) {
+		if !slices.Contains(sources, req.SourceName) {
+			sources = append(sources, req.SourceName)
+		}
+	}
+	slices.Sort(sources)
+	return sources, nil
+}
+
+// match returns the requests q selects, in order.
+func (m *Memory) match(q Query) []*db.Request {
+	var matched []*db.Request
+	for _, req := range m.requests {
+		if matches(req, q) {
+			matched = append(matched, req)
+		}
+	}
+	slices.SortFunc(matched, func(a, b *db.Request) int {
+		c := a.CreatedAt.Compare(b.CreatedAt)
+		if c == 0 {
+			c = compareIDs(a.ID, b.ID)
+		}
+		if !q.Oldest {
+			c = -c
+		}
+		return c
+	})
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+	}
+	return matched
+}
+
+func matches(req *db.Request, q Query) bool {
+	switch {
+	case len(q.SourceNames) > 0 && !slices.Contains(q.SourceNames, req.SourceName),
+		len(q.AppNames) > 0 && !slices.Contains(q.AppNames, req.AppName),
+		q.Kind != "" && req.Kind != q.Kind,
+		q.Question != "" && req.Question != q.Question,
+		q.ThreadID != 0 && req.ThreadID != q.ThreadID,
+		len(q.Statuses) > 0 && !slices.Contains(q.Statuses, req.Status),
+		slices.Contains(q.ExcludeStatuses, req.Status),
+		!q.Since.IsZero() && req.CreatedAt.Before(q.Since),
+		!q.Until.IsZero() && !req.CreatedAt.Before(q.Until):
+		return false
+	}
+	if q.After == nil {
+		return true
+	}
+	c := req.CreatedAt.Compare(q.After.CreatedAt)
+	if c == 0 {
+		c = compareIDs(req.ID, q.After.ID)
+	}
+	if q.Oldest {
+		return c > 0
+	}
+	return c < 0
+}
+
+func compareIDs(a, b uint) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (m *Memory) Respond(id uint, response string, at time.Time) error {
+	return m.transition(id, "pending", func(req *db.Request) {
+		req.Response = response
+		req.Status = "responded"
+		req.RespondedAt = &at
+	})
+}
+
+func (m *Memory) Timeout(id uint, response string) error {
+	return m.transition(id, "pending", func(req *db.Request) {
+		req.Response = response
+		req.Status = "timed_out"
+	})
+}
+
+func (m *Memory) Cancel(id uint) error {
+	return m.transition(id, "pending", func(req *db.Request) { req.Status = "cancelled" })
+}
+
+func (m *Memory) Orphan(id uint) error {
+	return m.transition(id, "pending", func(req *db.Request) { req.Status = "orphaned" })
+}
+
+func (m *Memory) Reclaim(id uint, ownerPID int, ownerHost string, expiresAt *time.Time) error {
+	return m.transition(id, "orphaned", func(req *db.Request) {
+		req.Status = "pending"
+		req.OwnerPID = ownerPID
+		req.OwnerHost = ownerHost
+		req.ExpiresAt = expiresAt
+	})
+}
+
+func (m *Memory) Acknowledge(id uint, at time.Time) error {
+	return m.transition(id, "unread", func(req *db.Request) {
+		req.Status = "acknowledged"
+		req.RespondedAt = &at
+	})
+}
+
+func (m *Memory) transition(id uint, from string, change func(*db.Request)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	req, ok := m.requests[id]
+	if !ok || req.Status != from {
+		return fmt.Errorf("request %d: %w", id, ErrConflict)
+	}
+	change(req)
+	req.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *Memory) Purge(ids []uint) ([]uint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted []uint
+	for _, id := range ids {
+		if req, ok := m.requests[id]; ok && req.Status == "pending" {
+			continue
+		}
+		delete(m.requests, id)
+		deleted = append(deleted, id)
+	}
+	return deleted, nil
+}
+
+// clone copies req so that callers cannot change what is stored.
+func clone(req *db.Request) *db.Request {
+	c := *req
+	c.Options = slices.Clone(req.Options)
+	c.Schema = slices.Clone(req.Schema)
+	c.Attachments = slices.Clone(req.Attachments)
+	return &c
+}
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SQL is the RequestStore of a SQLite or PostgreSQL database migrated by
+// db.Migrate.
+type SQL struct {
+	db *gorm.DB
+}
+
+// NewSQL returns the store of database.
+func NewSQL(database *gorm.DB) *SQL {
+	return &SQL{db: database}
+}
+
+func (s *SQL) Create(req *db.Request) error {
+	req.CreatedAt, req.UpdatedAt = req.CreatedAt.UTC(), req.UpdatedAt.UTC()
+	req.ExpiresAt, req.RespondedAt = utc(req.ExpiresAt), utc(req.RespondedAt)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(req).Error; err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		if req.ThreadID != 0 {
+			return nil
+		}
+		if err := tx.Model(&db.Request{}).Where("id = ?", req.ID).UpdateColumn("thread_id", req.ID).Error; err != nil {
+			return fmt.Errorf("failed to start thread for request %d: %w", req.ID, err)
+		}
+		req.ThreadID = req.ID
+		return nil
+	})
+}
+
+func (s *SQL) Get(id uint) (*db.Request, error) {
+	var req db.Request
+	if err := s.db.Preload("Attachments").First(&req, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("request %d %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to load request %d: %w", id, err)
+	}
+	return &req, nil
+}
+
+func (s *SQL) List(q Query) ([]db.Request, error) {
+	var requests []db.Request
+	if err := s.page(s.filter(s.db.Preload("Attachments"), q), q).Find(&requests).Error; err != nil {
+		return nil, fmt.Errorf("failed to list requests: %w", err)
+	}
+	return requests, nil
+}
+
+func (s *SQL) Count(q Query) (int64, error) {
+	var n int64
+	if err := s.filter(s.db.Model(&db.Request{}), q).Count(&n).Error; err != nil {
+		return 0, fmt.Errorf("failed to count requests: %w", err)
+	}
+	return n, nil
+}
+
+func (s *SQL) IDs(q Query) ([]uint, error) {
+	var ids []uint
+	if err := s.page(s.filter(s.db.Model(&db.Request{}), q), q).Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to list requests: %w", err)
+	}
+	return ids, nil
+}
+
+func (s *SQL) Sources(q Query) ([]string, error) {
+	var sources []string
+	if err := s.filter(s.db.Model(&db.Request{}), q).Distinct().Order("source_name").Pluck("source_name", &sources).Error; err != nil {
+		return nil, fmt.Errorf("failed to list sources: %w", err)
+	}
+	return sources, nil
+}
+
+// Times are stored and compared in UTC; see db.Open.
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// filter applies everything in q but the order, the cursor and the limit.
+func (s *SQL) filter(query *gorm.DB, q Query) *gorm.DB {
+	if len(q.SourceNames) > 0 {
+		query = query.Where("source_name IN ?", q.SourceNames)
+	}
+	if len(q.AppNames) > 0 {
+		query = query.Where("app_name IN ?", q.AppNames)
+	}
+	if q.Kind != "" {
+		query = query.Where("kind = ?", q.Kind)
+	}
+	if q.Question != "" {
+		query = query.Where("question = ?", q.Question)
+	}
+	if q.ThreadID != 0 {
+		query = query.Where("thread_id = ?", q.ThreadID)
+	}
+	if len(q.Statuses) > 0 {
+		query = query.Where("status IN ?", q.Statuses)
+	}
+	if len(q.ExcludeStatuses) > 0 {
+		query = query.Where("status NOT IN ?", q.ExcludeStatuses)
+	}
+	if !q.Since.IsZero() {
+		query = query.Where("created_at >= ?", q.Since.UTC())
+	}
+	if !q.Until.IsZero() {
+		query = query.Where("created_at < ?", q.Until.UTC())
+	}
+	return query
+}
+
+// page applies the order, the cursor and the limit of q.
+func (s *SQL) page(query *gorm.DB, q Query) *gorm.DB {
+	op := "<"
+	if q.Oldest {
+		op = ">"
+		query = query.Order("created_at ASC, id ASC")
+	} else {
+		query = query.Order("created_at DESC, id DESC")
+	}
+	if q.After != nil {
+		at := q.After.CreatedAt.UTC()
+		query = query.Where(fmt.Sprintf("(created_at %s ? OR (created_at = ? AND id %s ?))", op, op), at, at, q.After.ID)
+	}
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+	return query
+}
+
+func (s *SQL) Respond(id uint, response string, at time.Time) error {
+	return s.transition(id, "pending", map[string]interface{}{
+		"response":     response,
+		"status":       "responded",
+		"responded_at": utc(&at),
+	})
+}
+
+func (s *SQL) Timeout(id uint, response string) error {
+	return s.transition(id, "pending", map[string]interface{}{
+		"response": response,
+		"status":   "timed_out",
+	})
+}
+
+func (s *SQL) Cancel(id uint) error {
+	return s.transition(id, "pending", map[string]interface{}{"status": "cancelled"})
+}
+
+func (s *SQL) Orphan(id uint) error {
+	return s.transition(id, "pending", map[string]interface{}{"status": "orphaned"})
+}
+
+func (s *SQL) Reclaim(id uint, ownerPID int, ownerHost string, expiresAt *time.Time) error {
+	return s.transition(id, "orphaned", map[string]interface{}{
+		"status":     "pending",
+		"owner_pid":  ownerPID,
+		"owner_host": ownerHost,
+		"expires_at": utc(expiresAt),
+	})
+}
+
+func (s *SQL) Acknowledge(id uint, at time.Time) error {
+	return s.transition(id, "unread", map[string]interface{}{
+		"status":       "acknowledged",
+		"responded_at": utc(&at),
+	})
+}
+
+// transition applies changes to request id if it is in status from, in a
+// single statement.
+func (s *SQL) transition(id uint, from string, changes map[string]interface{}) error {
+	result := s.db.Model(&db.Request{}).Where("id = ? AND status = ?", id, from).Updates(changes)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update request %d: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("request %d: %w", id, ErrConflict)
+	}
+	return nil
+}
+
+func (s *SQL) Purge(ids []uint) ([]uint, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	// A single statement rather than a read-then-write transaction,
+	// which SQLite refuses to upgrade while another connection writes.
+	err := s.db.Unscoped().Where("id IN ? AND status <> ?", ids, "pending").Delete(&db.Request{}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete requests: %w", err)
+	}
+	var kept []uint
+	if err := s.db.Unscoped().Model(&db.Request{}).Where("id IN ?", ids).Pluck("id", &kept).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete requests: %w", err)
+	}
+	var deleted []uint
+	for _, id := range ids {
+		if !slices.Contains(kept, id) {
+			deleted = append(deleted, id)
+		}
+	}
+	if len(deleted) > 0 {
+		if err := s.db.Unscoped().Where("request_id IN ?", deleted).Delete(&db.Attachment{}).Error; err != nil {
+			return deleted, fmt.Errorf("failed to delete attachments: %w", err)
+		}
+	}
+	return deleted, nil
+}
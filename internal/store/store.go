@@ -0,0 +1,111 @@
+// Package store keeps requests. RequestStore is what the manager and the
+// web server use to create, answer, look up and delete them; SQL
+// implements it on the SQLite or PostgreSQL database opened by db.Init,
+// and Memory keeps requests in memory for tests.
+//
+// Only requests go through the store. Attachments, messages, search and
+// export work on the SQL database directly.
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+)
+
+// ErrNotFound is returned when a request does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned by a status change when the request does not
+// exist or is no longer in the status the change starts from, typically
+// because another process changed it first.
+var ErrConflict = errors.New("request is missing or its status changed")
+
+// RequestStore keeps requests. Status changes are atomic: each applies
+// only if the request is still in the status it starts from, and returns
+// ErrConflict otherwise.
+type RequestStore interface {
+	// Create stores req, setting its ID, UUID and timestamps. A request
+	// without a ThreadID starts a thread of its own. Attachments are not
+	// stored.
+	Create(req *db.Request) error
+	// Get returns request id with its attachments, or ErrNotFound.
+	Get(id uint) (*db.Request, error)
+	// List returns the requests matching q with their attachments.
+	List(q Query) ([]db.Request, error)
+	// Count counts the requests matching q, ignoring After and Limit.
+	Count(q Query) (int64, error)
+	// IDs returns the ids of the requests matching q, in List order.
+	IDs(q Query) ([]uint, error)
+	// Sources lists the distinct sources of the requests matching q.
+	Sources(q Query) ([]string, error)
+
+	// Respond answers a pending request.
+	Respond(id uint, response string, at time.Time) error
+	// Timeout closes a pending request with response, its default.
+	Timeout(id uint, response string) error
+	// Cancel closes a pending request whose agent stopped waiting.
+	Cancel(id uint) error
+	// Orphan closes a pending request whose agent is gone.
+	Orphan(id uint) error
+	// Reclaim makes an orphaned request pending again for a new owner.
+	Reclaim(id uint, ownerPID int, ownerHost string, expiresAt *time.Time) error
+	// Acknowledge marks an unread notification as seen.
+	Acknowledge(id uint, at time.Time) error
+
+	// Purge permanently deletes the requests ids and their attachment
+	// rows, skipping pending ones, and returns the ids it deleted.
+	// Attachment files are left to the caller.
+	Purge(ids []uint) ([]uint, error)
+}
+
+// Query selects requests. Zero fields do not filter. Requests are ordered
+// newest first by creation time, then id.
+type Query struct {
+	// SourceNames and AppNames match any of the given names, so a
+	// one-element list with an empty name matches requests without one.
+	SourceNames []string
+	AppNames    []string
+	Kind        string
+	Question    string
+	ThreadID    uint
+	Statuses    []string
+	// ExcludeStatuses leaves out requests in any of these statuses.
+	ExcludeStatuses []string
+	// Since is inclusive, Until exclusive.
+	Since time.Time
+	Until time.Time
+	// Oldest orders oldest first instead.
+	Oldest bool
+	// After starts after this position in the order, for paging.
+	After *Cursor
+	Limit int
+}
+
+// Cursor is a position in the order of a Query: that of the request
+// created at CreatedAt with id ID.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+var instance RequestStore
+
+// Get returns the store set with InitWithStore or, by default, the SQL
+// store of db.Get. It is nil until either is set up.
+func Get() RequestStore {
+	if instance != nil {
+		return instance
+	}
+	if d := db.Get(); d != nil {
+		return NewSQL(d)
+	}
+	return nil
+}
+
+// InitWithStore replaces the default store (useful for testing). Nil
+// restores it.
+func InitWithStore(s RequestStore) {
+	instance = s
+}
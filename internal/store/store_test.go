@@ -0,0 +1,251 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"gorm.io/gorm"
+)
+
+// stores returns one empty store of every kind, so each test checks that
+// Memory behaves like the SQL store. Set RISHVAN_TEST_DATABASE_URL to an
+// empty PostgreSQL database to include it; its tables are dropped after
+// every test.
+func stores(t *testing.T) map[string]RequestStore {
+	t.Helper()
+	all := map[string]RequestStore{
+		"memory": NewMemory(),
+		"sqlite": NewSQL(openTestDB(t, filepath.Join(t.TempDir(), "test.db"))),
+	}
+	if url := os.Getenv("RISHVAN_TEST_DATABASE_URL"); url != "" {
+		all["postgres"] = NewSQL(openTestDB(t, url))
+	}
+	return all
+}
+
+func openTestDB(t *testing.T, dsn string) *gorm.DB {
+	t.Helper()
+	d, err := db.Open(dsn)
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	if err := db.Migrate(d); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		if d.Dialector.Name() == "postgres" {
+			d.Migrator().DropTable(&db.Attachment{}, &db.Message{}, &db.Request{}, "schema_migrations")
+		}
+		if sqlDB, err := d.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return d
+}
+
+// create stores a request from source created at the given time.
+func create(t *testing.T, s RequestStore, source, status string, at time.Time) *db.Request {
+	t.Helper()
+	req := &db.Request{SourceName: source, AppName: "app", Question: "q " + at.Format(time.TimeOnly), Status: status}
+	req.CreatedAt = at
+	if err := s.Create(req); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	return req
+}
+
+func TestCreateAndGet(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			req := &db.Request{SourceName: "vscode", AppName: "app", Question: "Which color?", Options: []db.Option{{Label: "blue"}}}
+			if err := s.Create(req); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			if req.ID == 0 || req.ThreadID != req.ID || len(req.UUID) != 36 {
+				t.Fatalf("expected id, own thread and uuid, got id %d thread %d uuid %q", req.ID, req.ThreadID, req.UUID)
+			}
+
+			got, err := s.Get(req.ID)
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			if got.Kind != "question" || got.Status != "pending" || got.Question != "Which color?" || len(got.Options) != 1 {
+				t.Errorf("unexpected request: %+v", got)
+			}
+
+			followUp := &db.Request{SourceName: "vscode", AppName: "app", Question: "Which shade?", ThreadID: req.ID, ParentID: &req.ID}
+			if err := s.Create(followUp); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			if followUp.ThreadID != req.ID {
+				t.Errorf("expected follow-up in thread %d, got %d", req.ID, followUp.ThreadID)
+			}
+
+			if _, err := s.Get(999); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStatusChanges(t *testing.T) {
+	now := time.Now()
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			req := create(t, s, "vscode", "pending", now)
+			if err := s.Respond(req.ID, "blue", now); err != nil {
+				t.Fatalf("Respond failed: %v", err)
+			}
+			got, _ := s.Get(req.ID)
+			if got.Status != "responded" || got.Response != "blue" || got.RespondedAt == nil {
+				t.Errorf("unexpected request after Respond: %+v", got)
+			}
+			for _, change := range []func() error{
+				func() error { return s.Respond(req.ID, "red", now) },
+				func() error { return s.Timeout(req.ID, "") },
+				func() error { return s.Cancel(req.ID) },
+				func() error { return s.Orphan(req.ID) },
+				func() error { return s.Cancel(999) },
+			} {
+				if err := change(); !errors.Is(err, ErrConflict) {
+					t.Errorf("expected ErrConflict, got %v", err)
+				}
+			}
+
+			req = create(t, s, "vscode", "pending", now)
+			if err := s.Orphan(req.ID); err != nil {
+				t.Fatalf("Orphan failed: %v", err)
+			}
+			expires := now.Add(time.Hour)
+			if err := s.Reclaim(req.ID, 42, "host-b", &expires); err != nil {
+				t.Fatalf("Reclaim failed: %v", err)
+			}
+			if err := s.Timeout(req.ID, "default"); err != nil {
+				t.Fatalf("Timeout failed: %v", err)
+			}
+			got, _ = s.Get(req.ID)
+			if got.Status != "timed_out" || got.Response != "default" || got.OwnerPID != 42 || got.OwnerHost != "host-b" {
+				t.Errorf("unexpected request after Timeout: %+v", got)
+			}
+
+			note := create(t, s, "vscode", "unread", now)
+			if err := s.Acknowledge(note.ID, now); err != nil {
+				t.Fatalf("Acknowledge failed: %v", err)
+			}
+			if err := s.Acknowledge(note.ID, now); !errors.Is(err, ErrConflict) {
+				t.Errorf("expected ErrConflict acknowledging twice, got %v", err)
+			}
+		})
+	}
+}
+
+func TestListCountAndPage(t *testing.T) {
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			var ids []uint
+			for i, source := range []string{"vscode", "cursor", "vscode", "", "vscode"} {
+				status := "responded"
+				if i == 2 {
+					status = "pending"
+				}
+				ids = append(ids, create(t, s, source, status, base.Add(time.Duration(i)*time.Minute)).ID)
+			}
+			// Same creation time as the last: the id breaks the tie.
+			ids = append(ids, create(t, s, "vscode", "responded", base.Add(4*time.Minute)).ID)
+
+			got, err := s.IDs(Query{})
+			if err != nil {
+				t.Fatalf("IDs failed: %v", err)
+			}
+			if want := []uint{ids[5], ids[4], ids[3], ids[2], ids[1], ids[0]}; !slices.Equal(got, want) {
+				t.Errorf("newest first: expected %v, got %v", want, got)
+			}
+
+			q := Query{SourceNames: []string{"vscode"}, ExcludeStatuses: []string{"pending"}, Oldest: true, Limit: 2}
+			page, err := s.List(q)
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if len(page) != 2 || page[0].ID != ids[0] || page[1].ID != ids[4] {
+				t.Fatalf("unexpected first page: %v", requestIDs(page))
+			}
+			q.After = &Cursor{CreatedAt: page[1].CreatedAt, ID: page[1].ID}
+			page, _ = s.List(q)
+			if len(page) != 1 || page[0].ID != ids[5] {
+				t.Errorf("unexpected second page: %v", requestIDs(page))
+			}
+			if n, err := s.Count(q); err != nil || n != 3 {
+				t.Errorf("expected count 3 ignoring the cursor, got %d (%v)", n, err)
+			}
+
+			noSource, _ := s.IDs(Query{SourceNames: []string{""}})
+			if !slices.Equal(noSource, []uint{ids[3]}) {
+				t.Errorf("expected only the request without a source, got %v", noSource)
+			}
+			window, _ := s.IDs(Query{Since: base.Add(time.Minute), Until: base.Add(3 * time.Minute), Statuses: []string{"responded"}})
+			if !slices.Equal(window, []uint{ids[1]}) {
+				t.Errorf("expected the responded request in the window, got %v", window)
+			}
+			sources, _ := s.Sources(Query{ExcludeStatuses: []string{"pending"}})
+			if !slices.Equal(sources, []string{"", "cursor", "vscode"}) {
+				t.Errorf("unexpected sources %q", sources)
+			}
+		})
+	}
+}
+
+func TestListWindowAcrossOffsets(t *testing.T) {
+	// 04:00 UTC on February 1st, still January 31st in New York.
+	est := time.FixedZone("EST", -5*60*60)
+	at := time.Date(2025, 1, 31, 23, 0, 0, 0, est)
+	midnight := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			req := create(t, s, "vscode", "responded", at)
+			if since, _ := s.IDs(Query{Since: midnight}); !slices.Equal(since, []uint{req.ID}) {
+				t.Errorf("expected the request since midnight UTC, got %v", since)
+			}
+			if until, _ := s.IDs(Query{Until: midnight}); len(until) != 0 {
+				t.Errorf("expected nothing until midnight UTC, got %v", until)
+			}
+		})
+	}
+}
+
+func TestPurgeSkipsPending(t *testing.T) {
+	now := time.Now()
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			closed := create(t, s, "vscode", "responded", now)
+			pending := create(t, s, "vscode", "pending", now)
+
+			deleted, err := s.Purge([]uint{closed.ID, pending.ID})
+			if err != nil {
+				t.Fatalf("Purge failed: %v", err)
+			}
+			if !slices.Equal(deleted, []uint{closed.ID}) {
+				t.Errorf("expected only %d deleted, got %v", closed.ID, deleted)
+			}
+			if _, err := s.Get(closed.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected the closed request gone, got %v", err)
+			}
+			if _, err := s.Get(pending.ID); err != nil {
+				t.Errorf("expected the pending request kept, got %v", err)
+			}
+		})
+	}
+}
+
+func requestIDs(requests []db.Request) []uint {
+	ids := make([]uint, len(requests))
+	for i, r := range requests {
+		ids[i] = r.ID
+	}
+	return ids
+}
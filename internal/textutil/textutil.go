@@ -0,0 +1,19 @@
+// Package textutil formats questions and answers for the terminal, as the
+// list and show commands and the tui print them.
+package textutil
+
+import "strings"
+
+// OneLine flattens s to a single line of at most n runes.
+func OneLine(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+// Indent indents every line of s by two spaces.
+func Indent(s string) string {
+	return "  " + strings.ReplaceAll(strings.TrimRight(s, "\n"), "\n", "\n  ")
+}
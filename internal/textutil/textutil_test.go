@@ -0,0 +1,18 @@
+package textutil
+
+import "testing"
+
+func TestOneLine(t *testing.T) {
+	if got := OneLine("Which\n  database   for\tthe invoices?", 80); got != "Which database for the invoices?" {
+		t.Errorf("expected whitespace to be collapsed, got %q", got)
+	}
+	if got := OneLine("ünïcödé text", 6); got != "ünïcö…" {
+		t.Errorf("expected a cut after 5 runes, got %q", got)
+	}
+}
+
+func TestIndent(t *testing.T) {
+	if got := Indent("first\nsecond\n"); got != "  first\n  second" {
+		t.Errorf("unexpected indentation %q", got)
+	}
+}
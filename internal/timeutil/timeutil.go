@@ -0,0 +1,24 @@
+// Package timeutil parses the times accepted by date filters, such as the
+// since and until parameters of the list and export APIs.
+package timeutil
+
+import (
+	"fmt"
+	"time"
+)
+
+// Parse reads an RFC 3339 time or a date, taken as midnight UTC. An empty
+// string is the zero time, which filters nothing.
+func Parse(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC 3339 time or YYYY-MM-DD, got %q", s)
+	}
+	return t, nil
+}
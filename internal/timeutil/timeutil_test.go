@@ -0,0 +1,29 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want time.Time
+	}{
+		{"", time.Time{}},
+		{"2025-02-01", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"2025-01-31T23:00:00-05:00", time.Date(2025, 2, 1, 4, 0, 0, 0, time.UTC)},
+	} {
+		got, err := Parse(tc.in)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tc.in, err)
+			continue
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+	if _, err := Parse("yesterday"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
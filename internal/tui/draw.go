@@ -0,0 +1,243 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/textutil"
+	"github.com/tejzpr/rishvan-mcp/internal/webserver"
+)
+
+const (
+	ansiReset = "\033[0m"
+	ansiBold  = "\033[1m"
+	ansiDim   = "\033[2m"
+	ansiRed   = "\033[31m"
+	ansiGreen = "\033[32m"
+	ansiAmber = "\033[33m"
+	ansiClear = "\033[H\033[2J"
+)
+
+// style wraps s in an ANSI code when colors are enabled.
+func (u *ui) style(code, s string) string {
+	if !u.opts.Color {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func (u *ui) printf(format string, args ...any) {
+	fmt.Fprintf(u.out, format, args...)
+}
+
+// notef prints a one-line message below whatever is on screen.
+func (u *ui) notef(format string, args ...any) {
+	u.printf("%s\n", u.style(ansiAmber, "» "+fmt.Sprintf(format, args...)))
+}
+
+func (u *ui) bell() {
+	if u.opts.Color {
+		u.printf("\a")
+	}
+}
+
+func (u *ui) drawList() {
+	if u.opts.Color {
+		u.printf(ansiClear)
+	}
+	u.printf("%s  %s  %d pending\n\n", u.style(ansiBold, "rishvan-mcp"), u.style(ansiDim, webserver.PrimaryURL()), len(u.pending))
+	if len(u.pending) == 0 {
+		u.printf("  %s\n", u.style(ansiDim, "No pending requests. Waiting for agents…"))
+	}
+	for _, r := range u.pending {
+		u.printf("  %s  %s  %s\n", u.style(ansiBold, fmt.Sprintf("#%d", r.ID)),
+			origin(&r), u.style(ansiDim, kindLabel(&r)+" · "+ago(r.CreatedAt)))
+		u.printf("       %s\n", textutil.OneLine(r.Question, 72))
+	}
+	if u.flash != "" {
+		u.printf("\n")
+		u.notef("%s", u.flash)
+	}
+	u.printf("\n%s\n", u.style(ansiDim, "<id> reply · s <id> show · h history · l refresh · ? help · q quit"))
+}
+
+func (u *ui) drawHistory() {
+	query := url.Values{}
+	if u.opts.Source != "" {
+		query.Set("source_name", u.opts.Source)
+	}
+	if u.opts.App != "" {
+		query.Set("app_name", u.opts.App)
+	}
+	query.Set("status", "responded,timed_out,cancelled,orphaned,unread,acknowledged")
+	query.Set("limit", strconv.Itoa(historySize))
+	page, err := webserver.RemoteListPage(query)
+	if err != nil {
+		u.notef("%v", err)
+		return
+	}
+
+	u.printf("\n%s\n\n", u.style(ansiBold, "History"))
+	for _, r := range page.Requests {
+		u.printf("  %s  %s  %s\n", u.style(ansiBold, fmt.Sprintf("#%d", r.ID)), origin(&r), u.statusLabel(r.Status))
+		u.printf("       Q: %s\n", textutil.OneLine(r.Question, 70))
+		if r.Response != "" {
+			u.printf("       A: %s\n", textutil.OneLine(r.Response, 70))
+		}
+	}
+	if len(page.Requests) == 0 {
+		u.printf("  %s\n", u.style(ansiDim, "Nothing answered yet."))
+	}
+	u.printf("\n")
+}
+
+func (u *ui) drawRequest(r *db.Request) {
+	if u.opts.Color {
+		u.printf(ansiClear)
+	}
+	u.printf("%s  %s  %s\n", u.style(ansiBold, fmt.Sprintf("Request #%d", r.ID)), origin(r), u.statusLabel(r.Status))
+	meta := kindLabel(r) + " · asked " + ago(r.CreatedAt)
+	if r.ParentID != nil {
+		meta += fmt.Sprintf(" · follow-up to #%d", *r.ParentID)
+	}
+	u.printf("%s\n\n", u.style(ansiDim, meta))
+	if r.ParentID != nil {
+		u.drawEarlier(r)
+	}
+	u.printf("%s\n", textutil.Indent(r.Question))
+
+	for i, opt := range r.Options {
+		line := fmt.Sprintf("  %d. %s", i+1, opt.Label)
+		if opt.Description != "" {
+			line += u.style(ansiDim, " - "+opt.Description)
+		}
+		if opt.Label == r.DefaultOption {
+			line += u.style(ansiGreen, " (recommended)")
+		}
+		u.printf("%s\n", line)
+	}
+	if len(r.Attachments) > 0 {
+		u.printf("\n%s\n", u.style(ansiDim, "Attachments:"))
+		for _, a := range r.Attachments {
+			from := ""
+			if a.FromHuman {
+				from = ", with the response"
+			}
+			u.printf("  %s %s\n    %s\n", a.Name, u.style(ansiDim, fmt.Sprintf("(%s, %d bytes%s)", a.MIMEType, a.Size, from)),
+				fmt.Sprintf("%s/api/requests/%d/attachments/%d", webserver.PrimaryURL(), r.ID, a.Index))
+		}
+	}
+	if len(r.Schema) > 0 {
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, r.Schema, "", "  ") != nil {
+			pretty.Reset()
+			pretty.Write(r.Schema)
+		}
+		u.printf("\n%s\n%s\n", u.style(ansiDim, "Expected JSON:"), textutil.Indent(pretty.String()))
+	}
+	if r.ExpiresAt != nil && r.Status == "pending" {
+		u.printf("\n%s\n", u.style(ansiAmber, "Expires "+r.ExpiresAt.Local().Format("15:04:05")))
+	}
+	if r.Response != "" {
+		u.printf("\n%s\n%s\n", u.style(ansiDim, "Response:"), textutil.Indent(r.Response))
+	}
+	u.printf("\n")
+}
+
+// drawEarlier prints the exchanges of r's thread that came before it.
+func (u *ui) drawEarlier(r *db.Request) {
+	thread, err := webserver.RemoteThread(r.ThreadID)
+	if err != nil {
+		u.notef("%v", err)
+		return
+	}
+	for _, t := range thread {
+		if t.ID >= r.ID {
+			break
+		}
+		u.printf("%s\n", u.style(ansiDim, fmt.Sprintf("  #%d Q: %s", t.ID, textutil.OneLine(t.Question, 66))))
+		if t.Response != "" {
+			u.printf("%s\n", u.style(ansiDim, "      A: "+textutil.OneLine(t.Response, 66)))
+		}
+	}
+	u.printf("\n")
+}
+
+func (u *ui) drawReplyPrompt(r *db.Request) {
+	switch r.Kind {
+	case "choice":
+		if r.AllowOther {
+			u.printf("%s\n", u.style(ansiDim, "Enter an option number, or type your own answer. .cancel to go back."))
+		} else {
+			u.printf("%s\n", u.style(ansiDim, "Enter an option number or label. .cancel to go back."))
+		}
+	case "form":
+		u.printf("%s\n", u.style(ansiDim, "Type a JSON object, then a line with a single '.' to send. .cancel to go back."))
+	default:
+		u.printf("%s\n", u.style(ansiDim, "Type your reply, then a line with a single '.' to send. .cancel to go back."))
+	}
+}
+
+func (u *ui) drawHelp() {
+	u.printf(`
+Commands:
+  <id>, r <id>   answer a pending request
+  s <id>         show a request, answered or not
+  a <id>         acknowledge a notification
+  h              show recent history
+  l              reload the pending list
+  q              quit
+
+While answering, end a free-text or form reply with a line containing only
+'.'. Choices take a single line: the option number or its label.
+'.cancel' returns to the list without answering.
+
+`)
+}
+
+func (u *ui) statusLabel(status string) string {
+	switch status {
+	case "pending":
+		return u.style(ansiAmber, status)
+	case "responded", "acknowledged":
+		return u.style(ansiGreen, status)
+	case "unread":
+		return u.style(ansiAmber, status)
+	case "timed_out", "cancelled", "orphaned":
+		return u.style(ansiRed, strings.ReplaceAll(status, "_", " "))
+	}
+	return status
+}
+
+func origin(r *db.Request) string {
+	if r.AppName == "" {
+		return r.SourceName
+	}
+	return r.SourceName + "/" + r.AppName
+}
+
+func kindLabel(r *db.Request) string {
+	if r.Kind == "" {
+		return "question"
+	}
+	return r.Kind
+}
+
+func ago(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
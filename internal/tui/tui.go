@@ -0,0 +1,374 @@
+// Package tui is a terminal responder for machines without a browser. It
+// talks to the primary exclusively through its HTTP API: the request list,
+// the /api/events stream and the respond endpoint.
+package tui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/textutil"
+	"github.com/tejzpr/rishvan-mcp/internal/webserver"
+)
+
+// historySize is how many answered or closed requests "h" prints.
+const historySize = 20
+
+// Options controls how the UI is drawn.
+type Options struct {
+	// Source and App restrict the UI to one source or app when set.
+	Source string
+	App    string
+	// Color enables ANSI colors and clearing the screen on redraw. It
+	// should only be set when the output is a terminal.
+	Color bool
+}
+
+// update is a change pushed by the primary, or a note about the event
+// stream itself.
+type update struct {
+	event string
+	id    uint
+	note  string
+}
+
+type ui struct {
+	opts Options
+	out  io.Writer
+
+	pending []db.Request
+	// replying is the request whose answer is being typed, or nil while
+	// the list is shown.
+	replying *db.Request
+	lines    []string
+	// flash is shown under the list until the next command, so redraws
+	// triggered by events don't swallow it.
+	flash string
+}
+
+// Run draws the pending list and reads commands from in until the user
+// quits, in reaches EOF or ctx is cancelled.
+func Run(ctx context.Context, in io.Reader, out io.Writer, opts Options) error {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	u := &ui{opts: opts, out: out}
+	if err := u.refresh(); err != nil {
+		return err
+	}
+	u.drawList()
+
+	input := make(chan string)
+	go func() {
+		defer close(input)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case input <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	updates := make(chan update)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		watch(ctx, updates)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-input:
+			if !ok {
+				return nil
+			}
+			if quit := u.handleLine(line); quit {
+				return nil
+			}
+		case up := <-updates:
+			u.handleUpdate(up)
+		}
+	}
+}
+
+// watch forwards events from the primary to updates, reconnecting with
+// backoff whenever the stream drops.
+func watch(ctx context.Context, updates chan<- update) {
+	send := func(up update) bool {
+		select {
+		case updates <- up:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	backoff := time.Second
+	connected := true
+	for ctx.Err() == nil {
+		events, err := webserver.RemoteSubscribe(ctx)
+		if err != nil {
+			if connected && !send(update{note: fmt.Sprintf("lost connection to %s, retrying…", webserver.PrimaryURL())}) {
+				return
+			}
+			connected = false
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			backoff = min(backoff*2, 10*time.Second)
+			continue
+		}
+		if !connected {
+			// Anything may have changed while we were away.
+			if !send(update{event: "reconnected"}) {
+				return
+			}
+		}
+		connected, backoff = true, time.Second
+
+		for ev := range events {
+			var data struct {
+				ID uint `json:"id"`
+			}
+			json.Unmarshal([]byte(ev.Data), &data)
+			if !send(update{event: ev.Name, id: data.ID}) {
+				return
+			}
+		}
+	}
+}
+
+func (u *ui) refresh() error {
+	query := url.Values{}
+	if u.opts.Source != "" {
+		query.Set("source_name", u.opts.Source)
+	}
+	if u.opts.App != "" {
+		query.Set("app_name", u.opts.App)
+	}
+	// Oldest first: that is the agent that has been waiting longest.
+	query.Set("status", "pending")
+	query.Set("order", "oldest")
+	requests, err := webserver.RemoteListRequests(query)
+	if err != nil {
+		return err
+	}
+	u.pending = requests
+	return nil
+}
+
+func (u *ui) handleUpdate(up update) {
+	if up.event == "" {
+		u.notef("%s", up.note)
+		return
+	}
+	if err := u.refresh(); err != nil {
+		u.notef("refresh failed: %v", err)
+		return
+	}
+
+	if u.replying == nil {
+		u.drawList()
+		switch up.event {
+		case "new-request":
+			u.bell()
+		case "new-notification":
+			u.announce(up.id)
+		}
+		return
+	}
+
+	// Keep the half-typed reply on screen; only interrupt it when the
+	// request it is for can no longer be answered.
+	if up.event == "new-notification" {
+		u.announce(up.id)
+		return
+	}
+	if up.id == u.replying.ID && up.event != "new-request" {
+		u.replying, u.lines = nil, nil
+		u.flash = fmt.Sprintf("request #%d was %s elsewhere; reply discarded", up.id, describeEvent(up.event))
+		u.drawList()
+		return
+	}
+	if up.event == "new-request" {
+		u.notef("new request #%d is waiting", up.id)
+		u.bell()
+	}
+}
+
+func (u *ui) handleLine(line string) (quit bool) {
+	u.flash = ""
+	if u.replying != nil {
+		u.handleReplyLine(line)
+		return false
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		u.drawList()
+		return false
+	}
+	switch cmd := fields[0]; cmd {
+	case "q", "quit", "exit":
+		return true
+	case "h", "history":
+		u.drawHistory()
+	case "l", "ls", "list":
+		if err := u.refresh(); err != nil {
+			u.notef("refresh failed: %v", err)
+		}
+		u.drawList()
+	case "s", "show", "r", "reply":
+		if len(fields) != 2 {
+			u.notef("usage: %s <id>", cmd)
+			return false
+		}
+		id, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			u.notef("invalid id %q", fields[1])
+			return false
+		}
+		if cmd == "s" || cmd == "show" {
+			u.show(uint(id))
+		} else {
+			u.startReply(uint(id))
+		}
+	case "a", "ack":
+		if len(fields) != 2 {
+			u.notef("usage: %s <id>", cmd)
+			return false
+		}
+		id, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			u.notef("invalid id %q", fields[1])
+			return false
+		}
+		if err := webserver.RemoteAcknowledge(uint(id)); err != nil {
+			u.notef("%v", err)
+			return false
+		}
+		u.notef("acknowledged notification #%d", id)
+	case "?", "help":
+		u.drawHelp()
+	default:
+		// A bare number is the common case: answer that request.
+		if id, err := strconv.ParseUint(cmd, 10, 64); err == nil && len(fields) == 1 {
+			u.startReply(uint(id))
+			return false
+		}
+		u.notef("unknown command %q; type ? for help", cmd)
+	}
+	return false
+}
+
+func (u *ui) startReply(id uint) {
+	for i := range u.pending {
+		if u.pending[i].ID == id {
+			req := u.pending[i]
+			u.replying, u.lines = &req, nil
+			u.drawRequest(&req)
+			u.drawReplyPrompt(&req)
+			return
+		}
+	}
+	u.notef("request #%d is not pending", id)
+}
+
+func (u *ui) handleReplyLine(line string) {
+	req := u.replying
+	switch strings.TrimSpace(line) {
+	case ".cancel":
+		u.replying, u.lines = nil, nil
+		u.drawList()
+		return
+	case ".":
+		u.submit(strings.Join(u.lines, "\n"))
+		return
+	}
+
+	// Choices are answered with a single line, by number or label.
+	if req.Kind == "choice" {
+		answer := strings.TrimSpace(line)
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(req.Options) {
+			answer = req.Options[n-1].Label
+		}
+		u.submit(answer)
+		return
+	}
+	u.lines = append(u.lines, line)
+}
+
+func (u *ui) submit(text string) {
+	req := u.replying
+	if strings.TrimSpace(text) == "" {
+		u.notef("response cannot be empty")
+		u.lines = nil
+		return
+	}
+	if err := webserver.RemoteRespond(req.ID, text); err != nil {
+		// Keep the request open so a rejected form or choice can be
+		// retyped without starting over.
+		u.notef("%v", err)
+		u.lines = nil
+		u.drawReplyPrompt(req)
+		return
+	}
+	u.replying, u.lines = nil, nil
+	u.flash = fmt.Sprintf("responded to request #%d", req.ID)
+	if err := u.refresh(); err != nil {
+		u.notef("refresh failed: %v", err)
+	}
+	u.drawList()
+}
+
+// announce prints a notification an agent just sent. Nobody waits on it,
+// so it is shown once rather than added to the pending list.
+func (u *ui) announce(id uint) {
+	req, err := webserver.RemoteGetRequest(id)
+	if err != nil {
+		u.notef("%v", err)
+		return
+	}
+	u.notef("notification #%d from %s: %s", req.ID, origin(req), textutil.OneLine(req.Question, 60))
+	u.printf("%s\n", u.style(ansiDim, fmt.Sprintf("  s %d to read it, a %d to acknowledge", req.ID, req.ID)))
+	u.bell()
+}
+
+func (u *ui) show(id uint) {
+	req, err := webserver.RemoteGetRequest(id)
+	if err != nil {
+		u.notef("%v", err)
+		return
+	}
+	u.drawRequest(req)
+}
+
+func describeEvent(event string) string {
+	switch event {
+	case "request-responded":
+		return "answered"
+	case "request-timed-out":
+		return "timed out"
+	case "request-cancelled":
+		return "cancelled"
+	case "request-orphaned":
+		return "orphaned"
+	}
+	return "changed"
+}
@@ -0,0 +1,182 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tejzpr/rishvan-mcp/internal/auth"
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/webserver"
+)
+
+// fakePrimary serves the subset of the primary's API the TUI uses and
+// records the responses posted to it.
+type fakePrimary struct {
+	mu        sync.Mutex
+	requests  []db.Request
+	responses map[string]string
+}
+
+func newFakePrimary(t *testing.T, requests ...db.Request) *fakePrimary {
+	t.Helper()
+	f := &fakePrimary{requests: requests, responses: map[string]string{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/requests", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		requests := []db.Request{}
+		for _, req := range f.requests {
+			if s := r.URL.Query().Get("status"); s == "" || strings.Contains(","+s+",", ","+req.Status+",") {
+				requests = append(requests, req)
+			}
+		}
+		if r.URL.Query().Has("limit") {
+			json.NewEncoder(w).Encode(webserver.RequestPage{Requests: requests, Total: int64(len(requests))})
+			return
+		}
+		json.NewEncoder(w).Encode(requests)
+	})
+	mux.HandleFunc("GET /api/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("POST /api/requests/{id}/respond", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Response string `json:"response"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.responses[r.PathValue("id")] = body.Response
+		for i := range f.requests {
+			if strconv.FormatUint(uint64(f.requests[i].ID), 10) == r.PathValue("id") {
+				f.requests[i].Status = "responded"
+				f.requests[i].Response = body.Response
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	oldBind, oldPort, oldDir := config.Bind, config.Port, config.DataDir
+	t.Cleanup(func() { config.Bind, config.Port, config.DataDir = oldBind, oldPort, oldDir })
+	config.DataDir = t.TempDir()
+	token, err := auth.Token()
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	config.Bind = host
+	config.Port, _ = strconv.Atoi(port)
+	return f
+}
+
+func (f *fakePrimary) response(id string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r, ok := f.responses[id]
+	return r, ok
+}
+
+func pendingRequest(id uint, kind, question string) db.Request {
+	r := db.Request{SourceName: "windsurf", AppName: "myproj", Kind: kind, Question: question, Status: "pending"}
+	r.ID = id
+	return r
+}
+
+func run(t *testing.T, input string) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := Run(context.Background(), strings.NewReader(input), &out, Options{}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return out.String()
+}
+
+func TestMultiLineReply(t *testing.T) {
+	f := newFakePrimary(t, pendingRequest(7, "question", "Run the migration now?"))
+
+	out := run(t, "7\nYes, but\nback up first.\n.\n")
+
+	got, ok := f.response("7")
+	if !ok {
+		t.Fatalf("no response posted; output:\n%s", out)
+	}
+	if got != "Yes, but\nback up first." {
+		t.Errorf("unexpected response %q", got)
+	}
+	if !strings.Contains(out, "responded to request #7") {
+		t.Errorf("expected confirmation in output:\n%s", out)
+	}
+}
+
+func TestChoiceByNumber(t *testing.T) {
+	req := pendingRequest(3, "choice", "Which env?")
+	req.Options = []db.Option{{Label: "staging"}, {Label: "production"}}
+	f := newFakePrimary(t, req)
+
+	run(t, "r 3\n2\n")
+
+	if got, _ := f.response("3"); got != "production" {
+		t.Errorf("expected option 2 to be sent by label, got %q", got)
+	}
+}
+
+func TestCancelReply(t *testing.T) {
+	f := newFakePrimary(t, pendingRequest(4, "question", "Proceed?"))
+
+	run(t, "4\nnever mind\n.cancel\nq\n")
+
+	if _, ok := f.response("4"); ok {
+		t.Error("expected .cancel not to post a response")
+	}
+}
+
+func TestHistoryShowsAnsweredRequests(t *testing.T) {
+	done := pendingRequest(1, "question", "Use Postgres?")
+	done.Status, done.Response = "responded", "No, stay on SQLite"
+	newFakePrimary(t, done, pendingRequest(2, "question", "Still open?"))
+
+	out := run(t, "h\n")
+
+	if !strings.Contains(out, "No, stay on SQLite") {
+		t.Errorf("expected the answer in history:\n%s", out)
+	}
+	if !strings.Contains(out, "1 pending") {
+		t.Errorf("expected the pending count in the list:\n%s", out)
+	}
+}
+
+func TestUnknownRequestIsNotAnswered(t *testing.T) {
+	f := newFakePrimary(t)
+
+	out := run(t, "99\nhello\n")
+
+	if !strings.Contains(out, "request #99 is not pending") {
+		t.Errorf("expected a not-pending note:\n%s", out)
+	}
+	if _, ok := f.response("99"); ok {
+		t.Error("expected no response to be posted")
+	}
+}
@@ -0,0 +1,123 @@
+package webserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tejzpr/rishvan-mcp/internal/attachment"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+)
+
+// maxCreateBody caps POST /api/requests: the attachment limit, base64
+// encoded, plus room for the rest of the request.
+const maxCreateBody = attachment.MaxTotal*4/3 + 1<<20
+
+// maxRespondBody caps a multipart POST /api/requests/{id}/respond, whose
+// files are sent as they are.
+const maxRespondBody = attachment.MaxTotal + 1<<20
+
+func isMultipart(r *http.Request) bool {
+	t, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return t == "multipart/form-data"
+}
+
+// parseRespondForm reads an answer sent as multipart/form-data: the
+// "response" or "data" field and any number of "files".
+func parseRespondForm(r *http.Request) (string, json.RawMessage, []db.Attachment, error) {
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		return "", nil, nil, fmt.Errorf("invalid body: %w", err)
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	var data json.RawMessage
+	if v := r.MultipartForm.Value["data"]; len(v) > 0 && v[0] != "" {
+		data = json.RawMessage(v[0])
+	}
+	files := r.MultipartForm.File["files"]
+	if len(files) > attachment.MaxCount {
+		return "", nil, nil, fmt.Errorf("%w: at most %d attachments are allowed", attachment.ErrInvalid, attachment.MaxCount)
+	}
+	var atts []db.Attachment
+	for _, fh := range files {
+		f, err := fh.Open()
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("failed to read %s: %w", fh.Filename, err)
+		}
+		content, err := io.ReadAll(io.LimitReader(f, attachment.MaxSize+1))
+		f.Close()
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("failed to read %s: %w", fh.Filename, err)
+		}
+		mimeType := attachment.Normalize(fh.Header.Get("Content-Type"))
+		if mimeType == "" || mimeType == "application/octet-stream" {
+			mimeType = attachment.DetectType(fh.Filename, content)
+		}
+		atts = append(atts, db.Attachment{Name: fh.Filename, MIMEType: mimeType, Data: content})
+	}
+	if err := attachment.Validate(atts); err != nil {
+		return "", nil, nil, err
+	}
+	return r.FormValue("response"), data, atts, nil
+}
+
+func attachmentNames(atts []db.Attachment) string {
+	names := make([]string, len(atts))
+	for i, a := range atts {
+		names[i] = filepath.Base(a.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// handleGetAttachment serves attachment n of a request. Responses are
+// sandboxed and never sniffed, so a file cannot run script in the UI's
+// origin whatever it contains.
+func handleGetAttachment(w http.ResponseWriter, r *http.Request) {
+	database := db.Get()
+	if database == nil {
+		http.Error(w, "database not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	n, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil || n < 0 {
+		http.Error(w, "invalid attachment index", http.StatusBadRequest)
+		return
+	}
+
+	var a db.Attachment
+	if err := database.Where("request_id = ? AND idx = ?", id, n).First(&a).Error; err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	f, err := attachment.Open(database, &a)
+	if err != nil {
+		http.Error(w, "attachment content is missing", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	disposition := "inline"
+	if !attachment.IsImage(a.MIMEType) && r.URL.Query().Get("download") != "" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Type", a.MIMEType)
+	if cd := mime.FormatMediaType(disposition, map[string]string{"filename": a.Name}); cd != "" {
+		w.Header().Set("Content-Disposition", cd)
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	// Attachments never change once stored.
+	http.ServeContent(w, r, "", a.CreatedAt, f)
+}
@@ -0,0 +1,125 @@
+package webserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tejzpr/rishvan-mcp/internal/auth"
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+)
+
+const (
+	// sessionCookie carries the API token for the web UI after it has
+	// redeemed a login ticket.
+	sessionCookie = "rishvan_session"
+
+	// csrfHeader carries the CSRF token on cookie-authenticated writes.
+	csrfHeader = "X-CSRF-Token"
+)
+
+// requireAuth rejects /api/* calls that present neither the bearer token
+// nor the session cookie. The health check stays open so other instances
+// can detect the primary, and the frontend's static files stay open so the
+// UI can explain how to log in.
+func requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/api/health" || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !authenticated(r) {
+			http.Error(w, "unauthorized: open the login link printed by `rishvan-mcp open`", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func authenticated(r *http.Request) bool {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return auth.Valid(token)
+	}
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		return auth.Valid(c.Value)
+	}
+	return false
+}
+
+// checkCSRF requires the CSRF token on requests authenticated by the
+// session cookie, which a browser attaches on its own. Bearer-token
+// clients are exempt: only a holder of the token can send that header.
+func checkCSRF(r *http.Request) bool {
+	if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		return true
+	}
+	if _, err := r.Cookie(sessionCookie); err != nil {
+		return true
+	}
+	return auth.ValidCSRF(r.Header.Get(csrfHeader))
+}
+
+// handleCSRF returns the CSRF token to an authenticated UI that was not
+// served by the primary, such as the Vite dev server.
+func handleCSRF(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.CSRFToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"csrf_token": token})
+}
+
+// handleTicket mints a one-time login URL. Secondaries call it so the
+// browser they open can log in to the primary.
+func handleTicket(w http.ResponseWriter, r *http.Request) {
+	ticket, err := auth.NewTicket()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"url": loginURL(ticket)})
+}
+
+// handleLogin redeems a ticket from a login URL, stores the API token in
+// an HttpOnly cookie and sends the browser on to the UI.
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	if !auth.Redeem(r.URL.Query().Get("ticket")) {
+		http.Error(w, "This login link has expired or was already used. Run `rishvan-mcp open` for a new one.", http.StatusForbidden)
+		return
+	}
+	token, err := auth.Token()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   config.TLSEnabled(),
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func loginURL(ticket string) string {
+	return fmt.Sprintf("%s/auth?ticket=%s", config.BaseURL(), ticket)
+}
+
+// LoginURL returns a one-time URL that logs a browser in to the web UI.
+// The primary mints the ticket itself; secondaries ask the primary.
+func LoginURL() (string, error) {
+	if IsPrimary() {
+		ticket, err := auth.NewTicket()
+		if err != nil {
+			return "", err
+		}
+		return loginURL(ticket), nil
+	}
+	return RemoteLoginURL()
+}
@@ -0,0 +1,69 @@
+package webserver
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/export"
+	"github.com/tejzpr/rishvan-mcp/internal/timeutil"
+)
+
+// handleExport downloads the request history as JSON Lines, CSV or a
+// Markdown decision log.
+func handleExport(w http.ResponseWriter, r *http.Request) {
+	database := db.Get()
+	if database == nil {
+		http.Error(w, "database not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = export.JSONL
+	}
+	if !export.ValidFormat(format) {
+		http.Error(w, fmt.Sprintf("unknown format %q", format), http.StatusBadRequest)
+		return
+	}
+	filter := export.Filter{SourceName: q.Get("source_name"), AppName: q.Get("app_name")}
+	var err error
+	if filter.Since, err = timeutil.Parse(q.Get("since")); err != nil {
+		http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Until, err = timeutil.Parse(q.Get("until")); err != nil {
+		http.Error(w, "invalid until: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="rishvan-%s.%s"`, time.Now().Format("20060102"), format))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	out := &startedWriter{ResponseWriter: w}
+	if err := export.Write(database, out, format, filter); err != nil {
+		log.Printf("rishvan-mcp: export failed: %v", err)
+		if !out.started {
+			w.Header().Del("Content-Disposition")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Headers are gone once the body has started; all that is left is
+		// to cut the download short.
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// startedWriter records whether a response body has started.
+type startedWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *startedWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
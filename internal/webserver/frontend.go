@@ -0,0 +1,38 @@
+package webserver
+
+import (
+	"bytes"
+	"html"
+	"io/fs"
+	"net/http"
+
+	"github.com/tejzpr/rishvan-mcp/internal/auth"
+)
+
+// frontendHandler serves the embedded UI. index.html is served with the
+// CSRF token in a <meta name="rishvan-csrf"> tag when the browser is
+// logged in; everything else comes straight from fsys.
+func frontendHandler(fsys fs.FS) http.Handler {
+	files := http.FileServer(http.FS(fsys))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" && r.URL.Path != "/index.html" {
+			files.ServeHTTP(w, r)
+			return
+		}
+
+		page, err := fs.ReadFile(fsys, "index.html")
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if authenticated(r) {
+			if token, err := auth.CSRFToken(); err == nil {
+				meta := []byte(`<meta name="rishvan-csrf" content="` + html.EscapeString(token) + `" />`)
+				page = bytes.Replace(page, []byte("</head>"), append(meta, []byte("</head>")...), 1)
+			}
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(page)
+	})
+}
@@ -0,0 +1,118 @@
+package webserver
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/store"
+	"github.com/tejzpr/rishvan-mcp/internal/timeutil"
+)
+
+const (
+	// defaultPageSize applies when a cursor is given without a limit.
+	defaultPageSize = 50
+	// maxPageSize caps the limit parameter of GET /api/requests.
+	maxPageSize = 500
+)
+
+// RequestPage is one page of GET /api/requests, returned when the caller
+// asks for pagination with limit or cursor.
+type RequestPage struct {
+	Requests []db.Request `json:"requests"`
+	// NextCursor fetches the following page; empty on the last one.
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total counts every request matching the filters, on all pages.
+	Total int64 `json:"total"`
+}
+
+// listParams are the query parameters of GET /api/requests.
+type listParams struct {
+	query store.Query
+	// paged is set when the caller asked for pages with limit or cursor.
+	paged bool
+	limit int
+}
+
+func encodeCursor(c store.Cursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.CreatedAt.Format(time.RFC3339Nano) + "|" + strconv.FormatUint(uint64(c.ID), 10)))
+}
+
+func decodeCursor(s string) (*store.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &store.Cursor{CreatedAt: createdAt, ID: uint(n)}, nil
+}
+
+func parseListParams(q url.Values) (listParams, error) {
+	var p listParams
+	if s := q.Get("source_name"); s != "" {
+		p.query.SourceNames = []string{s}
+	}
+	if s := q.Get("app_name"); s != "" {
+		p.query.AppNames = []string{s}
+	}
+
+	for _, s := range strings.Split(q.Get("status"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			p.query.Statuses = append(p.query.Statuses, s)
+		}
+	}
+
+	var err error
+	if p.query.Since, err = timeutil.Parse(q.Get("since")); err != nil {
+		return p, fmt.Errorf("invalid since: %w", err)
+	}
+	if p.query.Until, err = timeutil.Parse(q.Get("until")); err != nil {
+		return p, fmt.Errorf("invalid until: %w", err)
+	}
+
+	switch q.Get("order") {
+	case "", "newest":
+	case "oldest":
+		p.query.Oldest = true
+	default:
+		return p, fmt.Errorf("order must be newest or oldest")
+	}
+
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxPageSize {
+			return p, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		p.limit, p.paged = n, true
+	}
+	if s := q.Get("cursor"); s != "" {
+		if p.query.After, err = decodeCursor(s); err != nil {
+			return p, err
+		}
+		p.paged = true
+		if p.limit == 0 {
+			p.limit = defaultPageSize
+		}
+	}
+	if p.paged {
+		// One request more than the limit tells whether another page
+		// follows.
+		p.query.Limit = p.limit + 1
+	}
+	return p, nil
+}
@@ -0,0 +1,107 @@
+package webserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/manager"
+)
+
+// maxListedMessages caps GET /api/messages; the UI only shows the latest.
+const maxListedMessages = 50
+
+// handleListMessages returns the latest messages, newest first, optionally
+// filtered by source_name and app_name.
+func handleListMessages(w http.ResponseWriter, r *http.Request) {
+	database := db.Get()
+	if database == nil {
+		http.Error(w, "database not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	query := database.Order("id DESC").Limit(maxListedMessages)
+	if sourceName := r.URL.Query().Get("source_name"); sourceName != "" {
+		query = query.Where("source_name = ?", sourceName)
+	}
+	if appName := r.URL.Query().Get("app_name"); appName != "" {
+		query = query.Where("app_name = ?", appName)
+	}
+
+	messages := []db.Message{}
+	if err := query.Find(&messages).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(messages)
+}
+
+// handlePostMessage queues a message from the human for the agents of a
+// source. An empty app_name addresses all of its apps.
+func handlePostMessage(w http.ResponseWriter, r *http.Request) {
+	if !checkCSRF(r) {
+		http.Error(w, "missing or invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
+	var body struct {
+		SourceName string `json:"source_name"`
+		AppName    string `json:"app_name"`
+		Body       string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	body.Body = strings.TrimSpace(body.Body)
+	if body.SourceName == "" || body.Body == "" {
+		http.Error(w, "source_name and body are required", http.StatusBadRequest)
+		return
+	}
+
+	msg, err := manager.PostMessage(body.SourceName, body.AppName, body.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(msg)
+}
+
+// handleAckMessages hands the unread messages for an agent's source and app
+// to a secondary instance and marks them read.
+func handleAckMessages(w http.ResponseWriter, r *http.Request) {
+	if !checkCSRF(r) {
+		http.Error(w, "missing or invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
+	var body struct {
+		SourceName string `json:"source_name"`
+		AppName    string `json:"app_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if body.SourceName == "" {
+		http.Error(w, "source_name is required", http.StatusBadRequest)
+		return
+	}
+
+	messages, err := manager.TakeMessages(body.SourceName, body.AppName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if messages == nil {
+		messages = []db.Message{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(messages)
+}
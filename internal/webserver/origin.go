@@ -0,0 +1,41 @@
+package webserver
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+)
+
+// allowedOrigin reports whether a browser origin may use the API.
+func allowedOrigin(origin string) bool {
+	return slices.Contains(config.Origins(), strings.TrimRight(origin, "/"))
+}
+
+// guardOrigin refuses state-changing requests that a browser marks as
+// coming from another site. A request with an Origin header must name an
+// allowed origin. Without one, Sec-Fetch-Site must say the request is
+// same-origin or user-initiated. Requests carrying neither header come from
+// non-browser clients such as secondaries and the CLI, and are left to the
+// token check.
+func guardOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if !allowedOrigin(origin) {
+				http.Error(w, "cross-origin request refused", http.StatusForbidden)
+				return
+			}
+		} else if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+			http.Error(w, "cross-site request refused", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
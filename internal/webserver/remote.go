@@ -1,77 +1,556 @@
 package webserver
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/attachment"
+	"github.com/tejzpr/rishvan-mcp/internal/auth"
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+	"github.com/tejzpr/rishvan-mcp/internal/manager"
 )
 
-// RemoteCreateRequest sends a question to the primary rishvan-mcp server
-// via HTTP and returns the created request ID.
-func RemoteCreateRequest(sourceName, appName, question string) (uint, error) {
-	payload, _ := json.Marshal(map[string]string{
-		"source_name": sourceName,
-		"app_name":    appName,
-		"question":    question,
+// RemoteCreateRequest sends a request to the primary rishvan-mcp server
+// via HTTP and returns the created request ID. req.ThreadID is set to the
+// thread the primary put the request in.
+func RemoteCreateRequest(req *db.Request) (uint, error) {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"source_name":      req.SourceName,
+		"app_name":         req.AppName,
+		"kind":             req.Kind,
+		"question":         req.Question,
+		"options":          req.Options,
+		"default_option":   req.DefaultOption,
+		"allow_other":      req.AllowOther,
+		"schema":           req.Schema,
+		"expires_at":       req.ExpiresAt,
+		"default_response": req.DefaultResponse,
+		"owner_pid":        req.OwnerPID,
+		"thread_id":        req.ThreadID,
+		"parent_id":        req.ParentID,
+		"attachments":      req.Attachments,
 	})
 
-	resp, err := http.Post(
-		fmt.Sprintf("%s/api/requests", BaseURL),
-		"application/json",
-		bytes.NewReader(payload),
-	)
+	httpReq, err := newAPIRequest(context.Background(), http.MethodPost, "/api/requests", bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	resp, err := apiClient(0).Do(httpReq)
 	if err != nil {
 		return 0, fmt.Errorf("failed to reach primary server: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusBadRequest {
+		// Keep the errors an agent can fix distinguishable, as they are
+		// for the primary.
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		for _, sentinel := range []error{manager.ErrUnknownThread, attachment.ErrInvalid} {
+			if rest, ok := strings.CutPrefix(strings.TrimSpace(string(msg)), sentinel.Error()); ok {
+				return 0, fmt.Errorf("%w%s", sentinel, rest)
+			}
+		}
+		return 0, fmt.Errorf("primary server rejected the request: %s", strings.TrimSpace(string(msg)))
+	}
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("primary server returned status %d", resp.StatusCode)
+		return 0, statusError(resp)
 	}
 
 	var result struct {
-		ID uint `json:"id"`
+		ID       uint `json:"id"`
+		ThreadID uint `json:"thread_id"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return 0, fmt.Errorf("failed to decode response: %w", err)
 	}
+	req.ThreadID = result.ThreadID
 	return result.ID, nil
 }
 
-// RemotePollResponse polls the primary server until the request is responded
-// to or the context is cancelled. Returns the human's response text.
+var (
+	// ErrTimedOut is returned by RemotePollResponse when the request timed
+	// out without a default response.
+	ErrTimedOut = errors.New("timed out waiting for a human response")
+
+	// ErrPrimaryGone is returned by RemotePollResponse when the primary
+	// server stopped answering; the caller may try to Promote.
+	ErrPrimaryGone = errors.New("primary server is gone")
+
+	// ErrUnauthorized is returned when the primary rejects this install's
+	// API token, usually because the two processes use different data
+	// directories.
+	ErrUnauthorized = errors.New("primary server rejected the API token; check that both instances use the same --data-dir")
+
+	// ErrRequestGone is returned by RemotePollResponse when the request
+	// was deleted while the agent waited for it.
+	ErrRequestGone = errors.New("request no longer exists")
+)
+
+// RemotePollResponse waits on the primary server's long-poll endpoint until
+// the request is responded to or the context is cancelled. Returns the
+// human's response text, or the default response if the request timed out.
+// Failed calls are retried with exponential backoff; once the primary stops
+// answering its health check ErrPrimaryGone is returned. A request that was
+// orphaned or deleted ends the wait with an error.
 func RemotePollResponse(ctx context.Context, reqID uint) (string, error) {
-	client := &http.Client{Timeout: 5 * time.Second}
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+	client := apiClient(remoteWait + 10*time.Second)
+	backoff := minBackoff
+	failures := 0
 
 	for {
-		select {
-		case <-ctx.Done():
+		result, err := remoteWaitOnce(ctx, client, reqID)
+		if ctx.Err() != nil {
 			return "", ctx.Err()
-		case <-ticker.C:
-			resp, err := client.Get(fmt.Sprintf("%s/api/requests/%d/poll", BaseURL, reqID))
-			if err != nil {
-				continue // transient error, retry
+		}
+		if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrRequestGone) {
+			return "", err
+		}
+		if err != nil {
+			failures++
+			if failures >= maxFailures && !isRishvanServer() {
+				return "", ErrPrimaryGone
+			}
+			// transient error, retry after backoff
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(backoff):
 			}
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+		backoff = minBackoff
+		failures = 0
 
-			var result struct {
-				ID       uint   `json:"id"`
-				Status   string `json:"status"`
-				Response string `json:"response"`
+		switch result.Status {
+		case "pending":
+			// The wait ended at its own timeout; wait again.
+		case "responded":
+			return result.Response, nil
+		case "timed_out":
+			if result.Response == "" {
+				return "", ErrTimedOut
 			}
-			decErr := json.NewDecoder(resp.Body).Decode(&result)
-			resp.Body.Close()
-			if decErr != nil {
-				continue
+			return result.Response, nil
+		case "cancelled":
+			return "", fmt.Errorf("request %d was cancelled", reqID)
+		case "orphaned":
+			return "", &manager.ClosedError{ID: reqID, Status: result.Status}
+		default:
+			// Nothing more will come for a status this binary does not
+			// know, and /wait would answer again at once.
+			return "", &manager.ClosedError{ID: reqID, Status: result.Status}
+		}
+	}
+}
+
+const (
+	// remoteWait is the server-side timeout requested from /wait.
+	remoteWait = 30 * time.Second
+	minBackoff = 500 * time.Millisecond
+	maxBackoff = 10 * time.Second
+	// maxFailures is how many consecutive failed waits trigger a health
+	// check of the primary.
+	maxFailures = 3
+)
+
+type pollResult struct {
+	ID       uint   `json:"id"`
+	Status   string `json:"status"`
+	Response string `json:"response"`
+}
+
+// remoteWaitOnce performs a single long-poll against the primary.
+func remoteWaitOnce(ctx context.Context, client *http.Client, reqID uint) (*pollResult, error) {
+	path := fmt.Sprintf("/api/requests/%d/wait?timeout=%d", reqID, int(remoteWait.Seconds()))
+	req, err := newAPIRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("request %d: %w", reqID, ErrRequestGone)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+	var result pollResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RemoteCancelRequest tells the primary server that the agent stopped
+// waiting for reqID.
+func RemoteCancelRequest(reqID uint) error {
+	req, err := newAPIRequest(context.Background(), http.MethodPost, fmt.Sprintf("/api/requests/%d/cancel", reqID), nil)
+	if err != nil {
+		return err
+	}
+	client := apiClient(5 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach primary server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+	return nil
+}
+
+// RemoteRespond answers reqID through the primary server's API.
+// Files in atts are uploaded as multipart form data along with it.
+func RemoteRespond(reqID uint, response string, atts ...db.Attachment) error {
+	payload, _ := json.Marshal(map[string]string{"response": response})
+	contentType := ""
+	timeout := 10 * time.Second
+	if len(atts) > 0 {
+		var buf bytes.Buffer
+		form := multipart.NewWriter(&buf)
+		form.WriteField("response", response)
+		for _, a := range atts {
+			h := textproto.MIMEHeader{}
+			h.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": "files", "filename": a.Name}))
+			h.Set("Content-Type", a.MIMEType)
+			part, err := form.CreatePart(h)
+			if err != nil {
+				return fmt.Errorf("failed to encode %s: %w", a.Name, err)
 			}
+			part.Write(a.Data)
+		}
+		if err := form.Close(); err != nil {
+			return fmt.Errorf("failed to encode response: %w", err)
+		}
+		payload, contentType = buf.Bytes(), form.FormDataContentType()
+		timeout = time.Minute
+	}
+
+	req, err := newAPIRequest(context.Background(), http.MethodPost, fmt.Sprintf("/api/requests/%d/respond", reqID), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	client := apiClient(timeout)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach primary server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return ErrUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("primary server refused the response (%d): %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// RemoteAcknowledge marks notification reqID as seen through the primary
+// server's API.
+func RemoteAcknowledge(reqID uint) error {
+	req, err := newAPIRequest(context.Background(), http.MethodPost, fmt.Sprintf("/api/requests/%d/acknowledge", reqID), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := apiClient(10 * time.Second).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach primary server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return ErrUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("primary server refused the acknowledgement (%d): %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// RemoteListRequests fetches requests from the primary server, filtered by
+// the same query parameters GET /api/requests accepts.
+func RemoteListRequests(query url.Values) ([]db.Request, error) {
+	path := "/api/requests"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	req, err := newAPIRequest(context.Background(), http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := apiClient(10 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach primary server: %w", err)
+	}
+	defer resp.Body.Close()
 
-			if result.Status == "responded" && result.Response != "" {
-				return result.Response, nil
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+	var requests []db.Request
+	if err := json.NewDecoder(resp.Body).Decode(&requests); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return requests, nil
+}
+
+// RemoteListPage fetches one page of requests from the primary server;
+// query must carry limit or cursor.
+func RemoteListPage(query url.Values) (*RequestPage, error) {
+	req, err := newAPIRequest(context.Background(), http.MethodGet, "/api/requests?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := apiClient(10 * time.Second).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach primary server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+	var page RequestPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &page, nil
+}
+
+// RemoteGetRequest fetches a single request from the primary server.
+func RemoteGetRequest(reqID uint) (*db.Request, error) {
+	httpReq, err := newAPIRequest(context.Background(), http.MethodGet, fmt.Sprintf("/api/requests/%d", reqID), nil)
+	if err != nil {
+		return nil, err
+	}
+	client := apiClient(10 * time.Second)
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach primary server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("request %d not found", reqID)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+	var req db.Request
+	if err := json.NewDecoder(resp.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &req, nil
+}
+
+// RemoteAttachment downloads attachment n of request reqID from the
+// primary server.
+func RemoteAttachment(reqID uint, n int) ([]byte, error) {
+	httpReq, err := newAPIRequest(context.Background(), http.MethodGet, fmt.Sprintf("/api/requests/%d/attachments/%d", reqID, n), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := apiClient(time.Minute).Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach primary server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, attachment.MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	return data, nil
+}
+
+// RemoteThread fetches the requests of a conversation thread from the
+// primary, oldest first.
+func RemoteThread(threadID uint) ([]db.Request, error) {
+	httpReq, err := newAPIRequest(context.Background(), http.MethodGet, fmt.Sprintf("/api/threads/%d", threadID), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := apiClient(10 * time.Second).Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach primary server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("thread %d not found", threadID)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+	var requests []db.Request
+	if err := json.NewDecoder(resp.Body).Decode(&requests); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return requests, nil
+}
+
+// RemoteTakeMessages fetches the unread messages for an agent of
+// sourceName working on appName from the primary, which marks them read.
+func RemoteTakeMessages(sourceName, appName string) ([]db.Message, error) {
+	payload, _ := json.Marshal(map[string]string{"source_name": sourceName, "app_name": appName})
+	httpReq, err := newAPIRequest(context.Background(), http.MethodPost, "/api/messages/ack", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := apiClient(10 * time.Second).Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach primary server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+	var messages []db.Message
+	if err := json.NewDecoder(resp.Body).Decode(&messages); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return messages, nil
+}
+
+// Event is one server-sent event from GET /api/events.
+type Event struct {
+	Name string
+	Data string
+}
+
+// RemoteSubscribe connects to the primary server's event stream. The
+// returned channel is closed when the stream ends or ctx is cancelled;
+// callers reconnect as they see fit.
+func RemoteSubscribe(ctx context.Context) (<-chan Event, error) {
+	req, err := newAPIRequest(ctx, http.MethodGet, "/api/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := apiClient(0).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach primary server: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, statusError(resp)
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		var ev Event
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if ev.Data != "" {
+					if ev.Name == "" {
+						ev.Name = "message"
+					}
+					select {
+					case events <- ev:
+					case <-ctx.Done():
+						return
+					}
+				}
+				ev = Event{}
+			case strings.HasPrefix(line, ":"):
+				// comment / keepalive
+			case strings.HasPrefix(line, "event:"):
+				ev.Name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			case strings.HasPrefix(line, "data:"):
+				if ev.Data != "" {
+					ev.Data += "\n"
+				}
+				ev.Data += strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
 			}
 		}
+	}()
+	return events, nil
+}
+
+// RemoteLoginURL asks the primary server for a one-time login URL for the
+// web UI.
+func RemoteLoginURL() (string, error) {
+	req, err := newAPIRequest(context.Background(), http.MethodPost, "/api/auth/ticket", nil)
+	if err != nil {
+		return "", err
+	}
+	client := apiClient(5 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach primary server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError(resp)
+	}
+	var result struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.URL, nil
+}
+
+// newAPIRequest builds a request for path on the primary server that
+// carries this install's API token.
+func newAPIRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	token, err := auth.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API token: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, PrimaryURL()+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// statusError describes a non-200 reply from the primary server.
+func statusError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusUnauthorized {
+		return ErrUnauthorized
 	}
+	return fmt.Errorf("primary server returned status %d", resp.StatusCode)
 }
@@ -0,0 +1,42 @@
+package webserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/tejzpr/rishvan-mcp/internal/db"
+)
+
+// handleSearchRequests finds requests by the words of their question or
+// response, best matches first, each with a highlighted snippet.
+func handleSearchRequests(w http.ResponseWriter, r *http.Request) {
+	database := db.Get()
+	if database == nil {
+		http.Error(w, "database not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	opts := db.SearchOptions{
+		SourceName: q.Get("source_name"),
+		AppName:    q.Get("app_name"),
+		Limit:      50,
+	}
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > 200 {
+			http.Error(w, "limit must be between 1 and 200", http.StatusBadRequest)
+			return
+		}
+		opts.Limit = n
+	}
+
+	results, err := db.Search(database, q.Get("q"), opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
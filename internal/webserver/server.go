@@ -2,84 +2,192 @@ package webserver
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
+	"log"
 	"net"
 	"net/http"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/tejzpr/rishvan-mcp/internal/attachment"
+	"github.com/tejzpr/rishvan-mcp/internal/auth"
 	"github.com/tejzpr/rishvan-mcp/internal/config"
 	"github.com/tejzpr/rishvan-mcp/internal/db"
 	"github.com/tejzpr/rishvan-mcp/internal/manager"
+	"github.com/tejzpr/rishvan-mcp/internal/schema"
+	"github.com/tejzpr/rishvan-mcp/internal/store"
 )
 
 const (
-	Port        = 56234
-	BaseURL     = "http://localhost:56234"
 	healthMagic = "rishvan-mcp-ok"
+
+	// defaultWait and maxWait bound how long /wait holds a request open.
+	defaultWait = 30 * time.Second
+	maxWait     = 2 * time.Minute
 )
 
 var (
 	startOnce sync.Once
 	startErr  error
-	// IsPrimary is true when this process owns the web server.
-	IsPrimary bool
+	// serveMu serialises attempts to bind the port (Start and Promote).
+	serveMu sync.Mutex
+	primary atomic.Bool
 )
 
 // EmbeddedFS will be set from main.go with the embedded frontend files
 var EmbeddedFS fs.FS
 
+// IsPrimary reports whether this process owns the web server.
+func IsPrimary() bool {
+	return primary.Load()
+}
+
 // Start tries to bind the port. If the port is already held by another
-// rishvan-mcp process it sets IsPrimary=false and returns nil so the
+// rishvan-mcp process it leaves IsPrimary false and returns nil so the
 // caller can fall back to the HTTP-based remote client.
 func Start() error {
 	startOnce.Do(func() {
+		serveMu.Lock()
+		defer serveMu.Unlock()
+
 		// Try to bind the port first
-		ln, err := net.Listen("tcp", fmt.Sprintf(":%d", Port))
+		ln, err := net.Listen("tcp", config.ListenAddr())
 		if err != nil {
 			// Port taken – check if it is another rishvan-mcp instance
 			if isRishvanServer() {
-				IsPrimary = false
 				return
 			}
-			startErr = fmt.Errorf("port %d in use by unknown process: %w", Port, err)
+			startErr = fmt.Errorf("port %d in use by unknown process: %w", config.Port, err)
 			return
 		}
-
-		IsPrimary = true
-
-		mux := http.NewServeMux()
-
-		// API routes
-		mux.HandleFunc("GET /api/health", handleHealth)
-		mux.HandleFunc("GET /api/requests", handleListRequests)
-		mux.HandleFunc("GET /api/requests/{id}", handleGetRequest)
-		mux.HandleFunc("POST /api/requests", handleCreateRequest)
-		mux.HandleFunc("POST /api/requests/{id}/respond", handleRespond)
-		mux.HandleFunc("GET /api/requests/{id}/poll", handlePollRequest)
-		mux.HandleFunc("OPTIONS /api/", handleCORS)
-		mux.HandleFunc("GET /api/events", handleSSE)
-		mux.HandleFunc("GET /api/ide", handleIDE)
-
-		// Serve embedded frontend
-		if EmbeddedFS != nil {
-			fileServer := http.FileServer(http.FS(EmbeddedFS))
-			mux.Handle("/", fileServer)
+		if ln, startErr = withTLS(ln); startErr != nil {
+			return
+		}
+		if startErr = serve(ln); startErr != nil {
+			ln.Close()
 		}
-
-		go func() {
-			_ = http.Serve(ln, corsMiddleware(mux))
-		}()
 	})
 	return startErr
 }
 
-// isRishvanServer checks whether the process on Port is a rishvan-mcp server.
+// Promote tries to take over the port after the primary has gone away.
+// Several secondaries may race; the one whose bind succeeds becomes the
+// new primary. It reports whether this process is now the primary.
+func Promote() bool {
+	serveMu.Lock()
+	defer serveMu.Unlock()
+
+	if primary.Load() {
+		return true
+	}
+	ln, err := net.Listen("tcp", config.ListenAddr())
+	if err != nil {
+		return false
+	}
+	inheritTLS()
+	if ln, err = withTLS(ln); err != nil {
+		log.Printf("rishvan-mcp: failed to take over as primary: %v", err)
+		return false
+	}
+	if err := serve(ln); err != nil {
+		ln.Close()
+		log.Printf("rishvan-mcp: failed to take over as primary: %v", err)
+		return false
+	}
+	return true
+}
+
+// serve opens the database, starts the HTTP server on ln and marks this
+// process as primary. Only the primary opens the database: secondaries go
+// through its API, so a single process writes to it.
+func serve(ln net.Listener) error {
+	if _, err := db.Init(); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	primary.Store(true)
+
+	if err := manager.Instance.RecoverPending(); err != nil {
+		log.Printf("rishvan-mcp: failed to recover pending requests: %v", err)
+	}
+	// Create the token up front so secondaries and the CLI can read it.
+	if _, err := auth.Token(); err != nil {
+		log.Printf("rishvan-mcp: failed to load API token, all API calls will be refused: %v", err)
+	}
+	if config.RetentionEnabled() {
+		go manager.Instance.RunJanitor(manager.RetentionFromConfig())
+	}
+	// The primaries of other hosts may answer requests in a shared
+	// PostgreSQL database; a SQLite file has only this one.
+	if config.DatabaseURL != "" {
+		go manager.Instance.RunSync()
+	}
+
+	go func() {
+		_ = http.Serve(ln, newHandler())
+	}()
+	return nil
+}
+
+// newHandler returns the primary's HTTP handler with all routes and
+// middleware installed.
+func newHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	// API routes
+	mux.HandleFunc("GET /api/health", handleHealth)
+	mux.HandleFunc("POST /api/auth/ticket", handleTicket)
+	mux.HandleFunc("GET /api/auth/csrf", handleCSRF)
+	mux.HandleFunc("GET /auth", handleLogin)
+	mux.HandleFunc("GET /api/requests", handleListRequests)
+	mux.HandleFunc("GET /api/requests/search", handleSearchRequests)
+	mux.HandleFunc("GET /api/requests/{id}", handleGetRequest)
+	mux.HandleFunc("POST /api/requests", handleCreateRequest)
+	mux.HandleFunc("DELETE /api/requests", handleClearHistory)
+	mux.HandleFunc("DELETE /api/requests/{id}", handleDeleteRequest)
+	mux.HandleFunc("POST /api/requests/{id}/respond", handleRespond)
+	mux.HandleFunc("GET /api/requests/{id}/poll", handlePollRequest)
+	mux.HandleFunc("GET /api/requests/{id}/wait", handleWaitRequest)
+	mux.HandleFunc("POST /api/requests/{id}/cancel", handleCancel)
+	mux.HandleFunc("POST /api/requests/{id}/acknowledge", handleAcknowledge)
+	mux.HandleFunc("GET /api/requests/{id}/attachments/{n}", handleGetAttachment)
+	mux.HandleFunc("GET /api/threads/{id}", handleGetThread)
+	mux.HandleFunc("GET /api/export", handleExport)
+	mux.HandleFunc("GET /api/messages", handleListMessages)
+	mux.HandleFunc("POST /api/messages", handlePostMessage)
+	mux.HandleFunc("POST /api/messages/ack", handleAckMessages)
+	mux.HandleFunc("OPTIONS /api/", handleCORS)
+	mux.HandleFunc("GET /api/events", handleSSE)
+	mux.HandleFunc("GET /api/ide", handleIDE)
+
+	// Serve embedded frontend
+	if EmbeddedFS != nil {
+		mux.Handle("/", frontendHandler(EmbeddedFS))
+	}
+
+	return corsMiddleware(guardOrigin(requireAuth(mux)))
+}
+
+// isRishvanServer checks whether the process on the configured port is a
+// rishvan-mcp server. It tries this process's own scheme first and then the
+// other one, so a secondary started without TLS flags still finds an HTTPS
+// primary, and remembers the one that answered.
 func isRishvanServer() bool {
-	client := &http.Client{Timeout: 2 * time.Second}
-	resp, err := client.Get(fmt.Sprintf("%s/api/health", BaseURL))
+	likely := PrimaryURL()
+	for _, u := range []string{likely, otherScheme(likely)} {
+		if healthy(u) {
+			detectedURL.Store(&[2]string{config.BaseURL(), u})
+			return true
+		}
+	}
+	return false
+}
+
+func healthy(base string) bool {
+	resp, err := apiClient(2 * time.Second).Get(base + "/api/health")
 	if err != nil {
 		return false
 	}
@@ -98,11 +206,17 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": healthMagic})
 }
 
+// corsMiddleware answers CORS only for allowed origins; other origins get
+// no CORS headers, so browsers keep their responses from the calling page.
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Add("Vary", "Origin")
+		if origin := r.Header.Get("Origin"); origin != "" && allowedOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+csrfHeader)
+		}
 		next.ServeHTTP(w, r)
 	})
 }
@@ -124,10 +238,23 @@ func IsRunning() bool {
 // request via HTTP. The primary instance stores it in the DB and manager.
 func handleCreateRequest(w http.ResponseWriter, r *http.Request) {
 	var body struct {
-		SourceName string `json:"source_name"`
-		AppName    string `json:"app_name"`
-		Question   string `json:"question"`
+		SourceName      string          `json:"source_name"`
+		AppName         string          `json:"app_name"`
+		Kind            string          `json:"kind"`
+		Question        string          `json:"question"`
+		Options         []db.Option     `json:"options"`
+		DefaultOption   string          `json:"default_option"`
+		AllowOther      bool            `json:"allow_other"`
+		Schema          json.RawMessage `json:"schema"`
+		ExpiresAt       *time.Time      `json:"expires_at"`
+		DefaultResponse string          `json:"default_response"`
+		OwnerPID        int             `json:"owner_pid"`
+		ThreadID        uint            `json:"thread_id"`
+		ParentID        *uint           `json:"parent_id"`
+		// Attachments carry their content base64-encoded in "data".
+		Attachments []db.Attachment `json:"attachments"`
 	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxCreateBody)
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
@@ -136,24 +263,67 @@ func handleCreateRequest(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "source_name, app_name and question are required", http.StatusBadRequest)
 		return
 	}
+	if body.Kind == "choice" && len(body.Options) == 0 {
+		http.Error(w, "choice requests need at least one option", http.StatusBadRequest)
+		return
+	}
+	if body.Kind == "form" {
+		if _, err := schema.Parse(body.Schema); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
-	reqID, _, err := manager.Instance.CreateRequest(body.SourceName, body.AppName, body.Question)
-	if err != nil {
+	req := db.Request{
+		SourceName:      body.SourceName,
+		AppName:         body.AppName,
+		Kind:            body.Kind,
+		Question:        body.Question,
+		Options:         body.Options,
+		DefaultOption:   body.DefaultOption,
+		AllowOther:      body.AllowOther,
+		Schema:          body.Schema,
+		ExpiresAt:       body.ExpiresAt,
+		DefaultResponse: body.DefaultResponse,
+		OwnerPID:        body.OwnerPID,
+		ThreadID:        body.ThreadID,
+		ParentID:        body.ParentID,
+		Attachments:     body.Attachments,
+	}
+	if err := attachment.Validate(req.Attachments); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Kind == "notification" {
+		// Nobody waits on a notification: store it and return.
+		if err := manager.Instance.Notify(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": req.ID, "thread_id": req.ThreadID})
+		return
+	}
+	if _, err := manager.Instance.Create(&req); err != nil {
+		if errors.Is(err, manager.ErrUnknownThread) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Notify frontend via SSE
-	manager.Broker.Publish(reqID, body.SourceName, body.AppName, body.Question)
+	manager.Broker.Publish(req.ID, req.SourceName, req.AppName, req.Question)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"id": reqID})
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": req.ID, "thread_id": req.ThreadID})
 }
 
 // handlePollRequest lets a secondary instance poll until a request is responded to.
 func handlePollRequest(w http.ResponseWriter, r *http.Request) {
-	database := db.Get()
-	if database == nil {
+	requests := store.Get()
+	if requests == nil {
 		http.Error(w, "database not initialized", http.StatusInternalServerError)
 		return
 	}
@@ -165,8 +335,8 @@ func handlePollRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req db.Request
-	if err := database.First(&req, id).Error; err != nil {
+	req, err := requests.Get(uint(id))
+	if err != nil {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
@@ -179,37 +349,114 @@ func handlePollRequest(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func handleListRequests(w http.ResponseWriter, r *http.Request) {
-	database := db.Get()
-	if database == nil {
+// handleWaitRequest is the long-poll variant of handlePollRequest. It blocks
+// until the request leaves the pending state or ?timeout= seconds pass
+// (default 30, at most 120), then reports the current status.
+func handleWaitRequest(w http.ResponseWriter, r *http.Request) {
+	requests := store.Get()
+	if requests == nil {
 		http.Error(w, "database not initialized", http.StatusInternalServerError)
 		return
 	}
 
-	var requests []db.Request
-	query := database.Order("created_at DESC")
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
 
-	// Filter by source_name if provided, otherwise show all
-	if sourceName := r.URL.Query().Get("source_name"); sourceName != "" {
-		query = query.Where("source_name = ?", sourceName)
+	timeout := defaultWait
+	if s := r.URL.Query().Get("timeout"); s != "" {
+		secs, err := strconv.Atoi(s)
+		if err != nil || secs < 0 {
+			http.Error(w, "invalid timeout", http.StatusBadRequest)
+			return
+		}
+		timeout = min(time.Duration(secs)*time.Second, maxWait)
 	}
 
-	if appName := r.URL.Query().Get("app_name"); appName != "" {
-		query = query.Where("app_name = ?", appName)
+	req, err := requests.Get(uint(id))
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	if req.Status == "pending" {
+		// Re-read after watching so a response that landed in between
+		// is not missed.
+		done := manager.Instance.Done(uint(id))
+		if req, err = requests.Get(uint(id)); err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if req.Status == "pending" {
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-timer.C:
+			case <-done:
+				if req, err = requests.Get(uint(id)); err != nil {
+					http.Error(w, "not found", http.StatusNotFound)
+					return
+				}
+			}
+		}
 	}
 
-	if err := query.Find(&requests).Error; err != nil {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":       req.ID,
+		"status":   req.Status,
+		"response": req.Response,
+	})
+}
+
+func handleListRequests(w http.ResponseWriter, r *http.Request) {
+	requests := store.Get()
+	if requests == nil {
+		http.Error(w, "database not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	params, err := parseListParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	list, err := requests.List(params.query)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(requests)
+	if !params.paged {
+		// Without limit or cursor the whole list is returned, as before
+		// pagination existed.
+		json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	page := RequestPage{Requests: list}
+	if page.Total, err = requests.Count(params.query); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(list) > params.limit {
+		page.Requests = list[:params.limit]
+		last := page.Requests[params.limit-1]
+		page.NextCursor = encodeCursor(store.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	json.NewEncoder(w).Encode(page)
 }
 
 func handleGetRequest(w http.ResponseWriter, r *http.Request) {
-	database := db.Get()
-	if database == nil {
+	requests := store.Get()
+	if requests == nil {
 		http.Error(w, "database not initialized", http.StatusInternalServerError)
 		return
 	}
@@ -221,8 +468,8 @@ func handleGetRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req db.Request
-	if err := database.First(&req, id).Error; err != nil {
+	req, err := requests.Get(uint(id))
+	if err != nil {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
@@ -231,7 +478,42 @@ func handleGetRequest(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(req)
 }
 
+// handleGetThread returns every request of a thread, oldest first, so the
+// UI can show a follow-up question together with the exchange before it.
+func handleGetThread(w http.ResponseWriter, r *http.Request) {
+	requests := store.Get()
+	if requests == nil {
+		http.Error(w, "database not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	thread, err := requests.List(store.Query{ThreadID: uint(id), Oldest: true})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(thread) == 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(thread)
+}
+
 func handleRespond(w http.ResponseWriter, r *http.Request) {
+	if !checkCSRF(r) {
+		http.Error(w, "missing or invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
 	idStr := r.PathValue("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
@@ -241,17 +523,39 @@ func handleRespond(w http.ResponseWriter, r *http.Request) {
 
 	var body struct {
 		Response string `json:"response"`
+		// Data carries the submitted values of a form request.
+		Data json.RawMessage `json:"data"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+	var atts []db.Attachment
+	if isMultipart(r) {
+		// Multipart bodies carry files the human attached to the answer.
+		r.Body = http.MaxBytesReader(w, r.Body, maxRespondBody)
+		body.Response, body.Data, atts, err = parseRespondForm(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
 	}
+	if len(body.Data) > 0 && string(body.Data) != "null" {
+		body.Response = string(body.Data)
+	}
+	if body.Response == "" && len(atts) > 0 {
+		body.Response = "See attached: " + attachmentNames(atts)
+	}
 	if body.Response == "" {
 		http.Error(w, "response cannot be empty", http.StatusBadRequest)
 		return
 	}
 
-	if err := manager.Instance.RespondToRequest(uint(id), body.Response); err != nil {
+	if err := manager.Instance.RespondWithAttachments(uint(id), body.Response, atts); err != nil {
+		var closed *manager.ClosedError
+		if errors.As(err, &closed) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -260,6 +564,107 @@ func handleRespond(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
+// handleCancel lets a secondary instance report that its agent stopped
+// waiting for a request.
+func handleCancel(w http.ResponseWriter, r *http.Request) {
+	if !checkCSRF(r) {
+		http.Error(w, "missing or invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := manager.Instance.CancelRequest(uint(id)); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleDeleteRequest permanently deletes a closed request and its
+// attachments.
+func handleDeleteRequest(w http.ResponseWriter, r *http.Request) {
+	if !checkCSRF(r) {
+		http.Error(w, "missing or invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := manager.Instance.DeleteRequest(uint(id)); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, manager.ErrPending):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleClearHistory deletes every closed request, optionally only those
+// of one source_name or app_name. Pending requests are kept.
+func handleClearHistory(w http.ResponseWriter, r *http.Request) {
+	if !checkCSRF(r) {
+		http.Error(w, "missing or invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
+	q := r.URL.Query()
+	deleted, err := manager.Instance.ClearHistory(q.Get("source_name"), q.Get("app_name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"deleted": deleted})
+}
+
+// handleAcknowledge marks a notification as seen.
+func handleAcknowledge(w http.ResponseWriter, r *http.Request) {
+	if !checkCSRF(r) {
+		http.Error(w, "missing or invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := manager.Instance.Acknowledge(uint(id)); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, store.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
 func handleSSE(w http.ResponseWriter, r *http.Request) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -270,7 +675,6 @@ func handleSSE(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	ch := manager.Broker.Subscribe()
 	defer manager.Broker.Unsubscribe(ch)
@@ -287,7 +691,7 @@ func handleSSE(w http.ResponseWriter, r *http.Request) {
 			if !ok {
 				return
 			}
-			fmt.Fprintf(w, "event: new-request\ndata: %s\n\n", msg)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", manager.EventName(msg), msg)
 			flusher.Flush()
 		}
 	}
@@ -1,13 +1,22 @@
 package webserver
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/tejzpr/rishvan-mcp/internal/auth"
 	"github.com/tejzpr/rishvan-mcp/internal/config"
 	"github.com/tejzpr/rishvan-mcp/internal/db"
 	"github.com/tejzpr/rishvan-mcp/internal/manager"
@@ -25,10 +34,11 @@ func setupTestDB(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to open test db: %v", err)
 	}
-	if err := d.AutoMigrate(&db.Request{}); err != nil {
+	if err := db.Migrate(d); err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
 	db.InitWithDB(d)
+	useTestDataDir(t)
 }
 
 func seedRequests(t *testing.T) {
@@ -273,15 +283,1108 @@ func TestCORSMiddleware(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 	})
 	handler := corsMiddleware(inner)
+	own := fmt.Sprintf("http://localhost:%d", config.Port)
 
 	req := httptest.NewRequest("GET", "/api/requests", nil)
+	req.Header.Set("Origin", own)
 	w := httptest.NewRecorder()
 	handler.ServeHTTP(w, req)
 
-	if w.Header().Get("Access-Control-Allow-Origin") != "*" {
-		t.Error("expected CORS origin header")
+	if w.Header().Get("Access-Control-Allow-Origin") != own {
+		t.Errorf("expected CORS origin header %q, got %q", own, w.Header().Get("Access-Control-Allow-Origin"))
 	}
 	if w.Header().Get("Access-Control-Allow-Methods") == "" {
 		t.Error("expected CORS methods header")
 	}
+
+	req = httptest.NewRequest("GET", "/api/requests", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("expected no CORS header for a foreign origin, got %q", got)
+	}
+}
+
+func TestHandleCreateChoiceRequestRequiresOptions(t *testing.T) {
+	setupTestDB(t)
+
+	body := strings.NewReader(`{"source_name":"test-ide","app_name":"app","kind":"choice","question":"which?"}`)
+	req := httptest.NewRequest("POST", "/api/requests", body)
+	w := httptest.NewRecorder()
+	handleCreateRequest(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for choice request without options, got %d", w.Code)
+	}
+}
+
+func listPage(t *testing.T, query string) RequestPage {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/api/requests?"+query, nil)
+	w := httptest.NewRecorder()
+	handleListRequests(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/requests?%s: expected 200, got %d: %s", query, w.Code, w.Body.String())
+	}
+	var page RequestPage
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatalf("failed to decode page: %v", err)
+	}
+	return page
+}
+
+func TestHandleListRequestsPagination(t *testing.T) {
+	setupTestDB(t)
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 7; i++ {
+		// Pairs share a creation time, so the cursor must break ties.
+		db.Get().Create(&db.Request{SourceName: "test-ide", AppName: "app", Question: fmt.Sprintf("q%d", i),
+			Status: "pending", Model: gorm.Model{CreatedAt: base.Add(time.Duration(i/2) * time.Hour)}})
+	}
+
+	var seen []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 4 {
+			t.Fatal("pagination did not end")
+		}
+		page := listPage(t, "limit=3&cursor="+cursor)
+		if page.Total != 7 {
+			t.Errorf("expected total 7, got %d", page.Total)
+		}
+		for _, r := range page.Requests {
+			seen = append(seen, r.Question)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if got := strings.Join(seen, ","); got != "q6,q5,q4,q3,q2,q1,q0" {
+		t.Errorf("expected every request once, newest first, got %s", got)
+	}
+
+	page := listPage(t, "order=oldest&limit=2")
+	if len(page.Requests) != 2 || page.Requests[0].Question != "q0" || page.Requests[1].Question != "q1" {
+		t.Errorf("expected q0,q1 oldest first, got %+v", page.Requests)
+	}
+	page = listPage(t, "order=oldest&limit=2&cursor="+page.NextCursor)
+	if len(page.Requests) != 2 || page.Requests[0].Question != "q2" {
+		t.Errorf("expected the second page to start at q2, got %+v", page.Requests)
+	}
+}
+
+func TestHandleListRequestsFilters(t *testing.T) {
+	setupTestDB(t)
+	seedRequests(t)
+	d := db.Get()
+	d.Model(&db.Request{}).Where("question = ?", "q1").Update("created_at", time.Date(2025, 1, 10, 8, 0, 0, 0, time.UTC))
+
+	page := listPage(t, "status=responded,pending&source_name=test-ide&limit=10")
+	if page.Total != 3 || page.NextCursor != "" {
+		t.Errorf("expected 3 requests on one page, got total %d, cursor %q", page.Total, page.NextCursor)
+	}
+	page = listPage(t, "status=responded&limit=10")
+	if page.Total != 1 || page.Requests[0].Question != "q3" {
+		t.Errorf("expected only q3, got %+v", page.Requests)
+	}
+	page = listPage(t, "until=2025-02-01&limit=10")
+	if page.Total != 1 || page.Requests[0].Question != "q1" {
+		t.Errorf("expected only q1 before February 2025, got %+v", page.Requests)
+	}
+	page = listPage(t, "since=2025-02-01T00:00:00Z&limit=10")
+	if page.Total != 3 {
+		t.Errorf("expected 3 requests since February 2025, got %d", page.Total)
+	}
+
+	// Filters without limit or cursor still return a plain array.
+	req := httptest.NewRequest("GET", "/api/requests?status=pending&order=oldest", nil)
+	w := httptest.NewRecorder()
+	handleListRequests(w, req)
+	var requests []db.Request
+	if err := json.NewDecoder(w.Body).Decode(&requests); err != nil {
+		t.Fatalf("expected a plain array: %v", err)
+	}
+	if len(requests) != 3 || requests[0].Question != "q1" {
+		t.Errorf("expected 3 pending requests, oldest first, got %+v", requests)
+	}
+
+	for _, query := range []string{"limit=0", "limit=abc", "order=sideways", "since=yesterday", "cursor=%21%21"} {
+		req := httptest.NewRequest("GET", "/api/requests?"+query, nil)
+		w := httptest.NewRecorder()
+		handleListRequests(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestHandleSearchRequests(t *testing.T) {
+	setupTestDB(t)
+	seedRequests(t)
+
+	req := httptest.NewRequest("GET", "/api/requests/search?q=done&source_name=test-ide", nil)
+	w := httptest.NewRecorder()
+	handleSearchRequests(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var results []db.SearchResult
+	if err := json.NewDecoder(w.Body).Decode(&results); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if len(results) != 1 || results[0].Question != "q3" || results[0].Snippet != "<mark>done</mark>" {
+		t.Errorf("unexpected results %+v", results)
+	}
+
+	req = httptest.NewRequest("GET", "/api/requests/search?q=done&limit=0", nil)
+	w = httptest.NewRecorder()
+	handleSearchRequests(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for limit=0, got %d", w.Code)
+	}
+}
+
+func TestHandleExport(t *testing.T) {
+	setupTestDB(t)
+	seedRequests(t)
+
+	req := httptest.NewRequest("GET", "/api/export?format=csv&source_name=test-ide&app_name=app-a", nil)
+	w := httptest.NewRecorder()
+	handleExport(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("expected a CSV content type, got %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, ".csv") {
+		t.Errorf("expected a .csv download, got %q", cd)
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "uuid,") {
+		t.Errorf("expected a header and two rows, got:\n%s", w.Body.String())
+	}
+
+	for _, query := range []string{"format=pdf", "since=yesterday"} {
+		req = httptest.NewRequest("GET", "/api/export?"+query, nil)
+		w = httptest.NewRecorder()
+		handleExport(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", query, w.Code)
+		}
+	}
+
+	// A failure before anything was sent is reported, not cut short.
+	db.Get().Exec("DROP TABLE requests")
+	req = httptest.NewRequest("GET", "/api/export", nil)
+	w = httptest.NewRecorder()
+	handleExport(w, req)
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Disposition") != "" {
+		t.Errorf("expected a plain 500, got %d with %v", w.Code, w.Header())
+	}
+}
+
+func TestHandleGetThread(t *testing.T) {
+	setupTestDB(t)
+	origInstance := manager.Instance
+	manager.Instance = manager.NewRequestManager()
+	defer func() { manager.Instance = origInstance }()
+
+	create := func(body string) map[string]uint {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/requests", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handleCreateRequest(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("create returned %d: %s", w.Code, w.Body.String())
+		}
+		var ids map[string]uint
+		json.NewDecoder(w.Body).Decode(&ids)
+		return ids
+	}
+	first := create(`{"source_name":"test-ide","app_name":"app","question":"first"}`)
+	create(`{"source_name":"test-ide","app_name":"app","question":"unrelated"}`)
+	second := create(fmt.Sprintf(`{"source_name":"test-ide","app_name":"app","question":"second","parent_id":%d}`, first["id"]))
+	if second["thread_id"] != first["thread_id"] {
+		t.Fatalf("expected follow-up in thread %d, got %d", first["thread_id"], second["thread_id"])
+	}
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/threads/%d", first["thread_id"]), nil)
+	req.SetPathValue("id", fmt.Sprint(first["thread_id"]))
+	w := httptest.NewRecorder()
+	handleGetThread(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var thread []db.Request
+	json.NewDecoder(w.Body).Decode(&thread)
+	if len(thread) != 2 || thread[0].Question != "first" || thread[1].Question != "second" {
+		t.Fatalf("expected [first second], got %+v", thread)
+	}
+	if thread[1].ParentID == nil || *thread[1].ParentID != thread[0].ID {
+		t.Errorf("expected second to follow up on first, got parent %v", thread[1].ParentID)
+	}
+
+	req = httptest.NewRequest("GET", "/api/threads/999", nil)
+	req.SetPathValue("id", "999")
+	w = httptest.NewRecorder()
+	handleGetThread(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown thread, got %d", w.Code)
+	}
+}
+
+func TestHandleCreateRequestUnknownParent(t *testing.T) {
+	setupTestDB(t)
+
+	body := strings.NewReader(`{"source_name":"test-ide","app_name":"app","question":"more?","parent_id":42}`)
+	req := httptest.NewRequest("POST", "/api/requests", body)
+	w := httptest.NewRecorder()
+	handleCreateRequest(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown parent, got %d", w.Code)
+	}
+}
+
+func TestCreateRequestWithAttachments(t *testing.T) {
+	setupTestDB(t)
+	useTestDataDir(t)
+	origInstance := manager.Instance
+	manager.Instance = manager.NewRequestManager()
+	defer func() { manager.Instance = origInstance }()
+
+	png := base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"))
+	body := fmt.Sprintf(`{"source_name":"test-ide","app_name":"app","question":"looks right?",
+		"attachments":[{"name":"shot.png","mime_type":"image/png","data":%q}]}`, png)
+	req := httptest.NewRequest("POST", "/api/requests", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handleCreateRequest(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/api/requests/1", nil)
+	req.SetPathValue("id", "1")
+	w = httptest.NewRecorder()
+	handleGetRequest(w, req)
+	var stored db.Request
+	json.NewDecoder(w.Body).Decode(&stored)
+	if len(stored.Attachments) != 1 || stored.Attachments[0].Name != "shot.png" || len(stored.Attachments[0].Data) != 0 {
+		t.Fatalf("expected attachment metadata without content, got %+v", stored.Attachments)
+	}
+
+	req = httptest.NewRequest("GET", "/api/requests/1/attachments/0", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("n", "0")
+	w = httptest.NewRecorder()
+	handleGetAttachment(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("expected image/png, got %q", got)
+	}
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" || !strings.Contains(w.Header().Get("Content-Security-Policy"), "sandbox") {
+		t.Errorf("expected sandboxed, unsniffed response, got %v", w.Header())
+	}
+	if !strings.HasPrefix(w.Body.String(), "\x89PNG") {
+		t.Errorf("unexpected content %q", w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/api/requests/1/attachments/1", nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("n", "1")
+	w = httptest.NewRecorder()
+	handleGetAttachment(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing attachment, got %d", w.Code)
+	}
+}
+
+func TestRespondWithUploadedFiles(t *testing.T) {
+	setupTestDB(t)
+	useTestDataDir(t)
+	origInstance := manager.Instance
+	manager.Instance = manager.NewRequestManager()
+	defer func() { manager.Instance = origInstance }()
+
+	id, ch, err := manager.Instance.CreateRequest("test-ide", "app", "what do you see?")
+	if err != nil {
+		t.Fatalf("CreateRequest failed: %v", err)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("files", "screenshot.png")
+	part.Write([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"))
+	part, _ = form.CreateFormFile("files", "server.log")
+	part.Write([]byte("panic: nil map\n"))
+	form.Close()
+
+	path := fmt.Sprintf("/api/requests/%d/respond", id)
+	req := httptest.NewRequest("POST", path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.SetPathValue("id", fmt.Sprint(id))
+	w := httptest.NewRecorder()
+	handleRespond(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := <-ch; got != "See attached: screenshot.png, server.log" {
+		t.Errorf("unexpected response %q", got)
+	}
+
+	var stored []db.Attachment
+	db.Get().Where("request_id = ?", id).Order("idx").Find(&stored)
+	if len(stored) != 2 || !stored[0].FromHuman {
+		t.Fatalf("unexpected attachments: %+v", stored)
+	}
+	// Types are detected when the upload does not say.
+	if stored[0].MIMEType != "image/png" || stored[1].MIMEType != "text/x-log" {
+		t.Errorf("unexpected types %q and %q", stored[0].MIMEType, stored[1].MIMEType)
+	}
+}
+
+func TestCreateRequestRejectsDisallowedAttachment(t *testing.T) {
+	setupTestDB(t)
+	useTestDataDir(t)
+
+	html := base64.StdEncoding.EncodeToString([]byte("<script>alert(1)</script>"))
+	body := fmt.Sprintf(`{"source_name":"test-ide","app_name":"app","question":"q",
+		"attachments":[{"name":"x.html","mime_type":"text/html","data":%q}]}`, html)
+	req := httptest.NewRequest("POST", "/api/requests", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handleCreateRequest(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an HTML attachment, got %d", w.Code)
+	}
+}
+
+func TestCreateAndAcknowledgeNotification(t *testing.T) {
+	setupTestDB(t)
+
+	body := strings.NewReader(`{"source_name":"test-ide","app_name":"app","kind":"notification","question":"done"}`)
+	req := httptest.NewRequest("POST", "/api/requests", body)
+	w := httptest.NewRecorder()
+	handleCreateRequest(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var stored db.Request
+	db.Get().First(&stored)
+	if stored.Kind != "notification" || stored.Status != "unread" {
+		t.Fatalf("expected an unread notification, got kind %q status %q", stored.Kind, stored.Status)
+	}
+
+	ack := func() int {
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/requests/%d/acknowledge", stored.ID), nil)
+		req.SetPathValue("id", fmt.Sprint(stored.ID))
+		w := httptest.NewRecorder()
+		handleAcknowledge(w, req)
+		return w.Code
+	}
+	if code := ack(); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if code := ack(); code != http.StatusConflict {
+		t.Errorf("expected 409 for a second acknowledgement, got %d", code)
+	}
+	stored.ID = 999
+	if code := ack(); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown notification, got %d", code)
+	}
+}
+
+func TestDeleteRequestsAndClearHistory(t *testing.T) {
+	setupTestDB(t)
+	useTestDataDir(t)
+	seedRequests(t)
+
+	del := func(id string) int {
+		req := httptest.NewRequest("DELETE", "/api/requests/"+id, nil)
+		req.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		handleDeleteRequest(w, req)
+		return w.Code
+	}
+	// q1 is pending, q3 answered.
+	if code := del("1"); code != http.StatusConflict {
+		t.Errorf("expected 409 for a pending request, got %d", code)
+	}
+	if code := del("3"); code != http.StatusOK {
+		t.Errorf("expected 200, got %d", code)
+	}
+	if code := del("3"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a deleted request, got %d", code)
+	}
+
+	d := db.Get()
+	d.Create(&db.Request{SourceName: "test-ide", AppName: "app-a", Question: "q5", Status: "cancelled"})
+	d.Create(&db.Request{SourceName: "other-ide", AppName: "app-a", Question: "q6", Status: "responded"})
+	req := httptest.NewRequest("DELETE", "/api/requests?source_name=test-ide", nil)
+	w := httptest.NewRecorder()
+	handleClearHistory(w, req)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"deleted":1}` {
+		t.Fatalf("unexpected clear result %d: %s", w.Code, w.Body.String())
+	}
+	var left []string
+	d.Model(&db.Request{}).Order("id").Pluck("question", &left)
+	if strings.Join(left, ",") != "q1,q2,q4,q6" {
+		t.Errorf("expected pending requests and other sources to remain, got %v", left)
+	}
+}
+
+func TestMessagesOutbox(t *testing.T) {
+	setupTestDB(t)
+
+	req := httptest.NewRequest("POST", "/api/messages", strings.NewReader(`{"source_name":"test-ide","app_name":"app","body":"  "}`))
+	w := httptest.NewRecorder()
+	handlePostMessage(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an empty message, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/api/messages", strings.NewReader(`{"source_name":"test-ide","app_name":"app","body":"stop"}`))
+	w = httptest.NewRecorder()
+	handlePostMessage(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	ack := func() []db.Message {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/messages/ack", strings.NewReader(`{"source_name":"test-ide","app_name":"app"}`))
+		w := httptest.NewRecorder()
+		handleAckMessages(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("ack returned %d", w.Code)
+		}
+		var messages []db.Message
+		json.NewDecoder(w.Body).Decode(&messages)
+		return messages
+	}
+	if got := ack(); len(got) != 1 || got[0].Body != "stop" {
+		t.Fatalf("expected the message, got %+v", got)
+	}
+	if got := ack(); len(got) != 0 {
+		t.Fatalf("expected no unread messages after ack, got %+v", got)
+	}
+
+	req = httptest.NewRequest("GET", "/api/messages?source_name=test-ide", nil)
+	w = httptest.NewRecorder()
+	handleListMessages(w, req)
+	var listed []db.Message
+	json.NewDecoder(w.Body).Decode(&listed)
+	if len(listed) != 1 || listed[0].ReadAt == nil {
+		t.Errorf("expected one read message in the list, got %+v", listed)
+	}
+}
+
+func TestPostMessageRequiresCSRFTokenWithCookie(t *testing.T) {
+	setupTestDB(t)
+	useTestDataDir(t)
+
+	req := httptest.NewRequest("POST", "/api/messages", strings.NewReader(`{"source_name":"test-ide","body":"stop"}`))
+	req.AddCookie(loginCookie(t))
+	w := httptest.NewRecorder()
+	newHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without a CSRF token, got %d", w.Code)
+	}
+}
+
+func TestCancelRequiresCSRFTokenWithCookie(t *testing.T) {
+	setupTestDB(t)
+	useTestDataDir(t)
+	seedRequests(t)
+
+	req := httptest.NewRequest("POST", "/api/requests/1/cancel", nil)
+	req.AddCookie(loginCookie(t))
+	w := httptest.NewRecorder()
+	newHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without a CSRF token, got %d", w.Code)
+	}
+	var stored db.Request
+	db.Get().First(&stored, 1)
+	if stored.Status != "pending" {
+		t.Errorf("expected request to stay pending, got %q", stored.Status)
+	}
+}
+
+func TestHandleRespondChoiceRejectsFreeText(t *testing.T) {
+	setupTestDB(t)
+	origInstance := manager.Instance
+	manager.Instance = manager.NewRequestManager()
+	defer func() { manager.Instance = origInstance }()
+
+	body := strings.NewReader(`{"source_name":"test-ide","app_name":"app","kind":"choice","question":"which?","options":[{"label":"a"},{"label":"b"}]}`)
+	req := httptest.NewRequest("POST", "/api/requests", body)
+	w := httptest.NewRecorder()
+	handleCreateRequest(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		ID uint `json:"id"`
+	}
+	json.NewDecoder(w.Body).Decode(&created)
+
+	req = httptest.NewRequest("POST", "/api/requests/1/respond", strings.NewReader(`{"response":"c"}`))
+	req.SetPathValue("id", fmt.Sprintf("%d", created.ID))
+	w = httptest.NewRecorder()
+	handleRespond(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for free-text answer, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/api/requests/1/respond", strings.NewReader(`{"response":"b"}`))
+	req.SetPathValue("id", fmt.Sprintf("%d", created.ID))
+	w = httptest.NewRecorder()
+	handleRespond(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for option answer, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleRespondFormValidation(t *testing.T) {
+	setupTestDB(t)
+	origInstance := manager.Instance
+	manager.Instance = manager.NewRequestManager()
+	defer func() { manager.Instance = origInstance }()
+
+	req := db.Request{
+		SourceName: "test-ide",
+		AppName:    "app",
+		Kind:       "form",
+		Question:   "deploy?",
+		Schema:     json.RawMessage(`{"type":"object","properties":{"branch":{"type":"string"},"approve":{"type":"boolean"}},"required":["branch","approve"]}`),
+	}
+	ch, err := manager.Instance.Create(&req)
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	r := httptest.NewRequest("POST", "/api/requests/1/respond", strings.NewReader(`{"data":{"branch":"main","approve":"yes"}}`))
+	r.SetPathValue("id", fmt.Sprintf("%d", req.ID))
+	w := httptest.NewRecorder()
+	handleRespond(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid submission, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "approve") {
+		t.Errorf("expected error to name the failing field, got %q", w.Body.String())
+	}
+
+	r = httptest.NewRequest("POST", "/api/requests/1/respond", strings.NewReader(`{"data":{"branch":"main","approve":true}}`))
+	r.SetPathValue("id", fmt.Sprintf("%d", req.ID))
+	w = httptest.NewRecorder()
+	handleRespond(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal([]byte(<-ch), &data); err != nil {
+		t.Fatalf("expected JSON response on channel: %v", err)
+	}
+	if data["branch"] != "main" || data["approve"] != true {
+		t.Errorf("unexpected form data %v", data)
+	}
+}
+
+func TestHandleRespondCancelledRequest(t *testing.T) {
+	setupTestDB(t)
+	origInstance := manager.Instance
+	manager.Instance = manager.NewRequestManager()
+	defer func() { manager.Instance = origInstance }()
+
+	id, _, err := manager.Instance.CreateRequest("test-ide", "app", "question?")
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/api/requests/1/cancel", nil)
+	req.SetPathValue("id", fmt.Sprintf("%d", id))
+	w := httptest.NewRecorder()
+	handleCancel(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 from cancel, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/api/requests/1/respond", strings.NewReader(`{"response":"too late"}`))
+	req.SetPathValue("id", fmt.Sprintf("%d", id))
+	w = httptest.NewRecorder()
+	handleRespond(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for cancelled request, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "cancelled") {
+		t.Errorf("expected explanation in body, got %q", w.Body.String())
+	}
+}
+
+func TestHandleWaitRequestReturnsOnResponse(t *testing.T) {
+	setupTestDB(t)
+	origInstance := manager.Instance
+	manager.Instance = manager.NewRequestManager()
+	defer func() { manager.Instance = origInstance }()
+
+	id, _, err := manager.Instance.CreateRequest("test-ide", "app", "question?")
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		manager.Instance.RespondToRequest(id, "answer")
+	}()
+
+	start := time.Now()
+	req := httptest.NewRequest("GET", "/api/requests/1/wait?timeout=10", nil)
+	req.SetPathValue("id", fmt.Sprintf("%d", id))
+	w := httptest.NewRecorder()
+	handleWaitRequest(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("wait did not return promptly after response (%v)", elapsed)
+	}
+	var result map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&result)
+	if result["status"] != "responded" || result["response"] != "answer" {
+		t.Errorf("unexpected wait result %v", result)
+	}
+}
+
+func TestHandleWaitRequestTimeout(t *testing.T) {
+	setupTestDB(t)
+	db.Get().Create(&db.Request{SourceName: "test-ide", AppName: "app", Question: "hello", Status: "pending"})
+
+	req := httptest.NewRequest("GET", "/api/requests/1/wait?timeout=0", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	handleWaitRequest(w, req)
+
+	var result map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&result)
+	if result["status"] != "pending" {
+		t.Errorf("expected status 'pending' after timeout, got %v", result["status"])
+	}
+}
+
+func TestHandleRespondOrphanedRequest(t *testing.T) {
+	setupTestDB(t)
+	db.Get().Create(&db.Request{SourceName: "test-ide", AppName: "app", Question: "hello", Status: "orphaned"})
+
+	req := httptest.NewRequest("POST", "/api/requests/1/respond", strings.NewReader(`{"response":"hi"}`))
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+	handleRespond(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for orphaned request, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "orphaned") {
+		t.Errorf("expected explanation in body, got %q", w.Body.String())
+	}
+}
+
+func useTestDataDir(t *testing.T) string {
+	t.Helper()
+	old := config.DataDir
+	config.DataDir = t.TempDir()
+	t.Cleanup(func() { config.DataDir = old })
+	token, err := auth.Token()
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	return token
+}
+
+func TestAPIRequiresToken(t *testing.T) {
+	setupTestDB(t)
+	token := useTestDataDir(t)
+	handler := newHandler()
+
+	cases := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"no token", "/api/requests", "", http.StatusUnauthorized},
+		{"wrong token", "/api/requests", "Bearer nope", http.StatusUnauthorized},
+		{"bearer token", "/api/requests", "Bearer " + token, http.StatusOK},
+		{"health is open", "/api/health", "", http.StatusOK},
+		{"ide needs token", "/api/ide", "", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, w.Code)
+		}
+	}
+}
+
+func TestRespondRequiresToken(t *testing.T) {
+	setupTestDB(t)
+	useTestDataDir(t)
+	seedRequests(t)
+
+	req := httptest.NewRequest("POST", "/api/requests/1/respond", strings.NewReader(`{"response":"yes, deploy"}`))
+	w := httptest.NewRecorder()
+	newHandler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+	var stored db.Request
+	db.Get().First(&stored, 1)
+	if stored.Status != "pending" {
+		t.Errorf("expected request to stay pending, got %q", stored.Status)
+	}
+}
+
+func TestLoginTicketSetsSessionCookie(t *testing.T) {
+	setupTestDB(t)
+	token := useTestDataDir(t)
+	handler := newHandler()
+
+	req := httptest.NewRequest("POST", "/api/auth/ticket", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for ticket, got %d", w.Code)
+	}
+	var body struct {
+		URL string `json:"url"`
+	}
+	json.NewDecoder(w.Body).Decode(&body)
+	_, query, ok := strings.Cut(body.URL, "/auth?")
+	if !ok {
+		t.Fatalf("unexpected login URL %q", body.URL)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth?"+query, nil))
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("expected redirect after login, got %d", w.Code)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookie || !cookies[0].HttpOnly {
+		t.Fatalf("expected an HttpOnly session cookie, got %v", cookies)
+	}
+
+	req = httptest.NewRequest("GET", "/api/requests", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected the session cookie to authenticate, got %d", w.Code)
+	}
+
+	// Tickets are single use.
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth?"+query, nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected a reused ticket to be refused, got %d", w.Code)
+	}
+}
+
+func TestTicketRequiresToken(t *testing.T) {
+	setupTestDB(t)
+	useTestDataDir(t)
+
+	w := httptest.NewRecorder()
+	newHandler().ServeHTTP(w, httptest.NewRequest("POST", "/api/auth/ticket", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", w.Code)
+	}
+}
+
+// loginCookie returns the session cookie a browser holds after login.
+func loginCookie(t *testing.T) *http.Cookie {
+	t.Helper()
+	token, err := auth.Token()
+	if err != nil {
+		t.Fatalf("failed to load token: %v", err)
+	}
+	return &http.Cookie{Name: sessionCookie, Value: token}
+}
+
+func TestCrossSitePostsAreRefused(t *testing.T) {
+	setupTestDB(t)
+	useTestDataDir(t)
+	seedRequests(t)
+	csrf, _ := auth.CSRFToken()
+	handler := newHandler()
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+	}{
+		{"foreign origin", map[string]string{"Origin": "https://evil.example"}},
+		{"other port on localhost", map[string]string{"Origin": "http://localhost:3000"}},
+		{"null origin", map[string]string{"Origin": "null"}},
+		{"cross-site fetch", map[string]string{"Sec-Fetch-Site": "cross-site"}},
+		{"same-site fetch", map[string]string{"Sec-Fetch-Site": "same-site"}},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("POST", "/api/requests/1/respond", strings.NewReader(`{"response":"yes, deploy"}`))
+		req.AddCookie(loginCookie(t))
+		req.Header.Set(csrfHeader, csrf)
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %d", tc.name, w.Code)
+		}
+	}
+
+	var stored db.Request
+	db.Get().First(&stored, 1)
+	if stored.Status != "pending" {
+		t.Errorf("expected request to stay pending, got %q", stored.Status)
+	}
+}
+
+func TestRespondRequiresCSRFTokenWithCookie(t *testing.T) {
+	setupTestDB(t)
+	token := useTestDataDir(t)
+	seedRequests(t)
+	csrf, _ := auth.CSRFToken()
+	handler := newHandler()
+	own := fmt.Sprintf("http://localhost:%d", config.Port)
+
+	respond := func(id int, setup func(*http.Request)) int {
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/requests/%d/respond", id), strings.NewReader(`{"response":"ok"}`))
+		req.Header.Set("Origin", own)
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		setup(req)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := respond(1, func(r *http.Request) { r.AddCookie(loginCookie(t)) }); code != http.StatusForbidden {
+		t.Errorf("cookie without CSRF token: expected 403, got %d", code)
+	}
+	if code := respond(1, func(r *http.Request) {
+		r.AddCookie(loginCookie(t))
+		r.Header.Set(csrfHeader, "wrong")
+	}); code != http.StatusForbidden {
+		t.Errorf("cookie with wrong CSRF token: expected 403, got %d", code)
+	}
+	if code := respond(1, func(r *http.Request) {
+		r.AddCookie(loginCookie(t))
+		r.Header.Set(csrfHeader, csrf)
+	}); code != http.StatusOK {
+		t.Errorf("cookie with CSRF token: expected 200, got %d", code)
+	}
+	if code := respond(2, func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}); code != http.StatusOK {
+		t.Errorf("bearer token: expected 200, got %d", code)
+	}
+}
+
+func TestNonBrowserClientsPassOriginCheck(t *testing.T) {
+	setupTestDB(t)
+	token := useTestDataDir(t)
+	seedRequests(t)
+
+	req := httptest.NewRequest("POST", "/api/requests/1/respond", strings.NewReader(`{"response":"from the cli"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	newHandler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 without Origin or Sec-Fetch-Site, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestIndexCarriesCSRFTokenOnlyWhenLoggedIn(t *testing.T) {
+	setupTestDB(t)
+	useTestDataDir(t)
+	csrf, _ := auth.CSRFToken()
+
+	old := EmbeddedFS
+	EmbeddedFS = fstest.MapFS{
+		"index.html": {Data: []byte("<html><head><title>x</title></head><body></body></html>")},
+	}
+	t.Cleanup(func() { EmbeddedFS = old })
+	handler := newHandler()
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(loginCookie(t))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), `<meta name="rishvan-csrf" content="`+csrf+`" />`) {
+		t.Errorf("expected CSRF meta tag for a logged-in browser, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if strings.Contains(w.Body.String(), "rishvan-csrf") {
+		t.Error("expected no CSRF token for an anonymous visitor")
+	}
+}
+
+// startTestPrimary serves newHandler on a loopback port, over TLS with a
+// self-signed certificate when useTLS is set, and points config at it.
+func startTestPrimary(t *testing.T, useTLS bool) {
+	t.Helper()
+	oldPort, oldBind := config.Port, config.Bind
+	oldCert, oldKey, oldSelfSigned := config.TLSCert, config.TLSKey, config.TLSSelfSigned
+	t.Cleanup(func() {
+		config.Port, config.Bind = oldPort, oldBind
+		config.TLSCert, config.TLSKey, config.TLSSelfSigned = oldCert, oldKey, oldSelfSigned
+	})
+
+	config.Bind = "127.0.0.1"
+	config.TLSCert, config.TLSKey, config.TLSSelfSigned = "", "", useTLS
+	if useTLS {
+		config.TLSCert, config.TLSKey = config.SelfSignedCertFile(), config.SelfSignedKeyFile()
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	config.Port = ln.Addr().(*net.TCPAddr).Port
+	if ln, err = withTLS(ln); err != nil {
+		t.Fatalf("withTLS failed: %v", err)
+	}
+	srv := &http.Server{Handler: newHandler()}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+}
+
+func TestHealthDetectionOverTLS(t *testing.T) {
+	setupTestDB(t)
+	useTestDataDir(t)
+	seedRequests(t)
+	startTestPrimary(t, true)
+
+	if !isRishvanServer() {
+		t.Fatal("expected the HTTPS primary to be detected")
+	}
+	if !strings.HasPrefix(PrimaryURL(), "https://") {
+		t.Errorf("expected an https primary URL, got %q", PrimaryURL())
+	}
+
+	// A secondary started without TLS flags finds the primary over HTTPS
+	// and trusts the self-signed certificate from the shared data dir.
+	config.TLSCert, config.TLSKey, config.TLSSelfSigned = "", "", false
+	if !isRishvanServer() {
+		t.Fatal("expected a plain-HTTP secondary to detect the HTTPS primary")
+	}
+	if !strings.HasPrefix(PrimaryURL(), "https://") {
+		t.Errorf("expected the detected https URL, got %q", PrimaryURL())
+	}
+	requests, err := RemoteListRequests(nil)
+	if err != nil {
+		t.Fatalf("RemoteListRequests over pinned TLS failed: %v", err)
+	}
+	if len(requests) != 4 {
+		t.Errorf("expected 4 requests, got %d", len(requests))
+	}
+}
+
+func TestPromotionKeepsTLS(t *testing.T) {
+	setupTestDB(t)
+	useTestDataDir(t)
+	startTestPrimary(t, true)
+
+	// A secondary without TLS flags that takes over from the HTTPS
+	// primary serves HTTPS with the certificate from the data dir.
+	config.TLSCert, config.TLSKey, config.TLSSelfSigned = "", "", false
+	if !isRishvanServer() {
+		t.Fatal("expected the HTTPS primary to be detected")
+	}
+	inheritTLS()
+	if config.TLSCert != config.SelfSignedCertFile() || config.TLSKey != config.SelfSignedKeyFile() {
+		t.Fatalf("expected the self-signed certificate to be inherited, got %q and %q", config.TLSCert, config.TLSKey)
+	}
+	if !strings.HasPrefix(config.BaseURL(), "https://") {
+		t.Errorf("expected an https base URL after taking over, got %q", config.BaseURL())
+	}
+}
+
+func TestPromotionOverPlainHTTPStaysPlain(t *testing.T) {
+	setupTestDB(t)
+	useTestDataDir(t)
+	startTestPrimary(t, false)
+
+	if !isRishvanServer() {
+		t.Fatal("expected the HTTP primary to be detected")
+	}
+	inheritTLS()
+	if config.TLSEnabled() {
+		t.Errorf("expected plain HTTP to be kept, got certificate %q", config.TLSCert)
+	}
+}
+
+func TestHealthDetectionOverPlainHTTP(t *testing.T) {
+	setupTestDB(t)
+	useTestDataDir(t)
+	startTestPrimary(t, false)
+
+	if !isRishvanServer() {
+		t.Fatal("expected the HTTP primary to be detected")
+	}
+	if !strings.HasPrefix(PrimaryURL(), "http://") {
+		t.Errorf("expected an http primary URL, got %q", PrimaryURL())
+	}
+}
+
+func TestTLSRejectsUntrustedCertificate(t *testing.T) {
+	setupTestDB(t)
+	useTestDataDir(t)
+	startTestPrimary(t, true)
+
+	// A client with a different data dir has no pinned certificate.
+	config.DataDir = t.TempDir()
+	config.TLSCert, config.TLSKey = "", ""
+	if healthy(otherScheme(config.BaseURL())) {
+		t.Error("expected an unpinned self-signed certificate to be refused")
+	}
+}
+
+func TestRemotePollResponseEndsForClosedRequests(t *testing.T) {
+	setupTestDB(t)
+	startTestPrimary(t, false)
+	orphan := db.Request{SourceName: "test-ide", AppName: "app", Question: "q", Status: "orphaned"}
+	db.Get().Create(&orphan)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var closed *manager.ClosedError
+	if _, err := RemotePollResponse(ctx, orphan.ID); !errors.As(err, &closed) || closed.Status != "orphaned" {
+		t.Errorf("expected a ClosedError for the orphaned request, got %v", err)
+	}
+	if _, err := RemotePollResponse(ctx, orphan.ID+1); !errors.Is(err, ErrRequestGone) {
+		t.Errorf("expected ErrRequestGone for a missing request, got %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("expected both waits to end without waiting out the context")
+	}
 }
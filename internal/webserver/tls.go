@@ -0,0 +1,130 @@
+package webserver
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tejzpr/rishvan-mcp/internal/certs"
+	"github.com/tejzpr/rishvan-mcp/internal/config"
+)
+
+// withTLS wraps ln in TLS when a certificate is configured, generating the
+// self-signed one first if asked to. Only the process that won the port
+// does this, so secondaries never race to write the certificate. ln is
+// closed on error.
+func withTLS(ln net.Listener) (net.Listener, error) {
+	if !config.TLSEnabled() {
+		return ln, nil
+	}
+	if config.TLSSelfSigned {
+		if err := certs.EnsureSelfSigned(config.TLSCert, config.TLSKey, selfSignedHosts()); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("failed to create self-signed certificate: %w", err)
+		}
+	}
+	cfg, err := certs.ServerConfig(config.TLSCert, config.TLSKey)
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return tls.NewListener(ln, cfg), nil
+}
+
+// inheritTLS makes a secondary started without TLS flags serve HTTPS with
+// the self-signed certificate of the data directory when it takes over
+// from a primary that served HTTPS, or most likely did. Agents and the
+// CLI then keep talking to the new primary over TLS rather than being
+// silently downgraded to plain HTTP.
+func inheritTLS() {
+	if config.TLSEnabled() || !strings.HasPrefix(PrimaryURL(), "https://") {
+		return
+	}
+	config.TLSSelfSigned = true
+	config.TLSCert, config.TLSKey = config.SelfSignedCertFile(), config.SelfSignedKeyFile()
+}
+
+// selfSignedHosts lists the names a generated certificate must cover.
+func selfSignedHosts() []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if name, err := os.Hostname(); err == nil {
+		hosts = append(hosts, name)
+	}
+	if ip := net.ParseIP(config.Bind); config.Bind != "" && (ip == nil || !ip.IsUnspecified()) {
+		hosts = append(hosts, config.Bind)
+	}
+	return hosts
+}
+
+var (
+	transportMu  sync.Mutex
+	transportKey string
+	transport    *http.Transport
+)
+
+// apiClient returns a client for calls to the primary. Besides the system
+// roots it trusts the configured certificate and the self-signed one in
+// the data directory, so a secondary pins the primary's generated
+// certificate without any flags of its own.
+func apiClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: apiTransport()}
+}
+
+func apiTransport() *http.Transport {
+	files := []string{config.SelfSignedCertFile()}
+	if config.TLSCert != "" {
+		files = append(files, config.TLSCert)
+	}
+	// Rebuild the trust pool whenever a certificate file changes, e.g.
+	// after the primary renewed its self-signed certificate.
+	var key strings.Builder
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			fmt.Fprintf(&key, "%s@%d;", f, info.ModTime().UnixNano())
+		}
+	}
+
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	if transport == nil || transportKey != key.String() {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = &tls.Config{RootCAs: certs.Pool(files...), MinVersion: tls.VersionTLS12}
+		transport, transportKey = t, key.String()
+	}
+	return transport
+}
+
+// detectedURL remembers which scheme the primary answered the health check
+// on, for when it disagrees with this process's own TLS settings.
+var detectedURL atomic.Pointer[[2]string] // {config.BaseURL(), primary URL}
+
+// PrimaryURL is the base URL of the primary's web UI and API: the one it
+// last answered a health check on, or config.BaseURL before any check.
+func PrimaryURL() string {
+	base := config.BaseURL()
+	if d := detectedURL.Load(); d != nil && d[0] == base {
+		return d[1]
+	}
+	// A self-signed certificate in the data dir means the primary most
+	// likely serves HTTPS even though this process has no TLS flags.
+	if !config.TLSEnabled() {
+		if _, err := os.Stat(config.SelfSignedCertFile()); err == nil {
+			return otherScheme(base)
+		}
+	}
+	return base
+}
+
+// otherScheme returns base with http and https swapped.
+func otherScheme(base string) string {
+	if rest, ok := strings.CutPrefix(base, "https://"); ok {
+		return "http://" + rest
+	}
+	return "https://" + strings.TrimPrefix(base, "http://")
+}